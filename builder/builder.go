@@ -17,6 +17,10 @@ func Suite(name string) component.Group {
 	return component.NewSuite(name)
 }
 
+func Parallel(name string) component.Parallel {
+	return component.NewParallel(name)
+}
+
 func Noop(name string) gestalt.Component {
 	return gestalt.NoopComponent(name)
 }
@@ -29,6 +33,14 @@ func Retry(tries int) component.Wrap {
 	return component.NewRetry(tries, time.Second)
 }
 
+func Timeout(timeout time.Duration) component.Wrap {
+	return component.NewTimeout(timeout)
+}
+
+func ForEach(listVar, itemVar string) component.Wrap {
+	return component.NewForEach(listVar, itemVar)
+}
+
 func Ensure(name string) component.Ensure {
 	return component.NewEnsure(name)
 }
@@ -49,6 +61,14 @@ func EXEC(name, cmd string, args ...string) exec.Cmd {
 	return exec.EXEC(name, cmd, args...)
 }
 
+func Interactive(name, cmd string, args ...string) exec.Interactive {
+	return exec.NewInteractive(name, cmd, args)
+}
+
+func Service(name string, cmd exec.Cmd) exec.Service {
+	return exec.NewService(name, cmd)
+}
+
 func Capture(columns ...string) exec.CmdFn {
 	return exec.Capture(columns...)
 }
@@ -57,6 +77,18 @@ func Columns(columns ...string) exec.Pipeline {
 	return exec.ParseColumns(columns...)
 }
 
+func Table() exec.Pipeline {
+	return exec.ParseTable()
+}
+
+func Regex(pattern string) exec.Pipeline {
+	return exec.ParseRegex(pattern)
+}
+
+func JSONLines() exec.Pipeline {
+	return exec.ParseJSONLines()
+}
+
 func Require(args ...string) vars.Meta {
 	return vars.NewMeta().Require(args...)
 }
@@ -1,10 +1,20 @@
 package builder_test
 
 import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/ovrclk/gestalt"
 	g "github.com/ovrclk/gestalt/builder"
+	"github.com/ovrclk/gestalt/exec"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestBG(t *testing.T) {
@@ -73,6 +83,372 @@ func TestEnsure(t *testing.T) {
 	}
 }
 
+func TestTimeout(t *testing.T) {
+	start := time.Now()
+	assertGestaltFails(t,
+		g.Suite("slow").
+			Run(g.Timeout(time.Second/10).
+				Run(g.SH("sleep", "sleep 5"))).
+			Run(g.SH("never", "false")), []string{})
+	assert.True(t, time.Since(start) < time.Second*5)
+
+	start = time.Now()
+	assertGestaltFails(t,
+		g.SH("sleep", "sleep 5"), []string{"--timeout", "100ms"})
+	assert.True(t, time.Since(start) < time.Second*5)
+}
+
+func TestTimeout_stopsSiblings(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gestalt-timeout")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	group := g.Group("inner").
+		Run(g.SH("slow", "sleep 2")).
+		Run(g.SH("after", "touch {{dir}}/after"))
+
+	assertGestaltFails(t,
+		g.Suite("top").Run(g.Timeout(time.Second/5).Run(group)),
+		[]string{"-sdir=" + dir})
+	_, err = os.Stat(dir + "/after")
+	assert.True(t, os.IsNotExist(err))
+
+	assertGestaltFails(t, group, []string{"-sdir=" + dir, "--timeout", "200ms"})
+	_, err = os.Stat(dir + "/after")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestKillGrace(t *testing.T) {
+	start := time.Now()
+	assertGestaltFails(t,
+		g.SH("stubborn", "trap '' TERM; sleep 5").KillGrace(time.Second/10),
+		[]string{"--timeout", "100ms"})
+	assert.True(t, time.Since(start) < time.Second*5)
+}
+
+func TestBG_trapTerm(t *testing.T) {
+	suite := g.Suite("server").
+		Run(g.BG().Run(g.SH("start", "trap 'exit 143' TERM; while true; do sleep 0.1; done"))).
+		Run(g.SH("ping", "sleep 0.2"))
+
+	assertGestaltSuccess(t, suite, []string{})
+}
+
+func TestBG_failFast(t *testing.T) {
+	suite := g.Suite("server").
+		Run(g.BG().Run(g.SH("crash", "sleep 0.1; false"))).
+		Run(g.SH("sleep", "sleep 5")).
+		Run(g.SH("never", "false"))
+
+	start := time.Now()
+	assertGestaltFails(t, suite, []string{"--bg-fail-fast"})
+	assert.True(t, time.Since(start) < time.Second*5)
+}
+
+func TestStreamOutput(t *testing.T) {
+	f, err := ioutil.TempFile("", "gestalt-log")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	f.Close()
+
+	suite := g.Suite("stream").
+		Run(g.SH("out", "echo", "to-stdout")).
+		Run(g.SH("err", "echo to-stderr 1>&2")).
+		Run(g.SH("quiet", "echo", "not-streamed").StreamOutput(false))
+
+	assertGestaltSuccess(t, suite, []string{"--stream-output", "--log-file", f.Name()})
+
+	buf, err := ioutil.ReadFile(f.Name())
+	require.NoError(t, err)
+
+	assert.Contains(t, string(buf), "/stream/out [stdout]: to-stdout\n")
+	assert.Contains(t, string(buf), "/stream/err [stderr]: to-stderr\n")
+	assert.NotContains(t, string(buf), "not-streamed")
+
+	// loggers which can't stream output don't.
+	l := &plainLogger{}
+	e := gestalt.NewEvaluatorWithLogger(l)
+	require.NoError(t, e.Evaluate(g.SH("out", "echo", "to-stdout")))
+	assert.NotContains(t, strings.Join(l.dumps, ""), "[stdout]")
+}
+
+// a Logger which isn't an OutputStreamer.
+type plainLogger struct {
+	dumps []string
+}
+
+func (l *plainLogger) Log() logrus.FieldLogger            { return logrus.New() }
+func (l *plainLogger) CloneFor(_ string) gestalt.Logger   { return l }
+func (l *plainLogger) Clone() gestalt.Logger              { return l }
+func (l *plainLogger) Start()                             {}
+func (l *plainLogger) Message(_ string, _ ...interface{}) {}
+func (l *plainLogger) Dump(msg string)                    { l.dumps = append(l.dumps, msg) }
+func (l *plainLogger) Stop(_ error)                       {}
+
+func TestExpectExit(t *testing.T) {
+	runComponent(t, g.SH("exit-2", "echo 'usage: bad flag' 1>&2; exit 2").
+		ExpectExit(1, 2).
+		StderrContains("usage:").
+		StderrMatches("bad [a-z]+"))
+
+	runComponent(t, g.SH("fails", "false").ExpectFailure())
+
+	assertGestaltFails(t, g.SH("exit-3", "exit 3").ExpectExit(2), []string{})
+	assertGestaltFails(t, g.SH("succeeds", "true").ExpectFailure(), []string{})
+	assertGestaltFails(t, g.SH("stderr", "echo oops 1>&2").StderrContains("usage"), []string{})
+}
+
+func TestStdin(t *testing.T) {
+	f, err := ioutil.TempFile("", "gestalt-stdin")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	_, err = f.WriteString("name: {{a}}\n")
+	require.NoError(t, err)
+	f.Close()
+
+	suite := g.Suite("stdin").
+		Run(g.SH("template", "grep -qx 'foo-bar'").Stdin("{{a}}-{{b}}")).
+		Run(g.SH("file", "grep -qx 'name: foo'").StdinFile(f.Name())).
+		Run(g.SH("var", "grep -qx 'foo-password'").StdinFrom("c"))
+
+	assertGestaltSuccess(t, suite, []string{"-sa=foo", "-sb=bar", "-sc={{a}}-password"})
+
+	assertGestaltFails(t, g.SH("missing", "cat").StdinFrom("missing"), []string{})
+}
+
+func TestEnv(t *testing.T) {
+	os.Setenv("GESTALT_TEST_ENV", "inherited")
+	defer os.Unsetenv("GESTALT_TEST_ENV")
+
+	suite := g.Suite("env").
+		AddEnv("KUBECONFIG", "/tmp/{{a}}").
+		Run(g.SH("inherit", `test "$GESTALT_TEST_ENV" = inherited -a -n "$PATH"`).
+			AddEnv("foo", "bar")).
+		Run(g.SH("override", `test "$FOO" = bar`).
+			AddEnv("foo", "bar")).
+		Run(g.SH("lower", `test "$foo" = bar -a -z "$FOO"`).
+			AddEnv("foo", "bar").UpperEnv(false)).
+		Run(g.EXEC("clean", "/bin/sh", "-c", `test -z "$GESTALT_TEST_ENV" -a "$KUBECONFIG" = /tmp/foo`).
+			CleanEnv()).
+		Run(g.SH("allow", `test "$GESTALT_TEST_ENV" = inherited -a -z "$HOME"`).
+			AllowEnv("GESTALT_TEST_ENV", "PATH")).
+		Run(g.Group("nested").
+			AddEnv("KUBECONFIG", "/tmp/nested").
+			Run(g.SH("group", `test "$KUBECONFIG" = /tmp/nested`)))
+
+	assertGestaltSuccess(t, suite, []string{"-sa=foo"})
+}
+
+func TestInteractive(t *testing.T) {
+	script := `[ -t 0 ] || exit 3
+printf 'Name: '; read name
+printf 'Continue? [y/n] '; read answer
+[ "$answer" = y ] && echo "created $name with id 42"`
+
+	suite := g.Suite("interactive").
+		Run(g.Interactive("prompt", "/bin/sh", "-c", script).
+			Expect("Name: ").
+			SendLine("{{a}}").
+			Expect(`\[y/n\]`).
+			SendLine("y").
+			Expect(`created (\S+) with id (\d+)`).
+			Emit("name", "id").
+			WithMeta(g.Export("name", "id"))).
+		Run(g.SH("check", `test "{{name}}-{{id}}" = foo-42`).
+			WithMeta(g.Require("name", "id")))
+
+	assertGestaltSuccess(t, suite, []string{"-sa=foo"})
+
+	start := time.Now()
+	assertGestaltFails(t,
+		g.Interactive("slow", "/bin/sh", "-c", "sleep 5").
+			ExpectWithin("never", time.Second/10), []string{})
+	assert.True(t, time.Since(start) < time.Second*5)
+}
+
+func TestService(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gestalt-service")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {}))
+	defer server.Close()
+
+	suite := g.Suite("service").
+		Run(g.Service("server",
+			g.SH("start", "sleep 0.2; echo listening on 8080; touch {{dir}}/ready; sleep 30")).
+			WaitOutput(`listening on (\d+)`, "port").
+			WaitFile("{{dir}}/ready").
+			WaitTCP(strings.TrimPrefix(server.URL, "http://")).
+			WaitHTTP(server.URL).
+			PID("pid")).
+		Run(g.SH("check", `test {{port}} = 8080 && kill -0 {{pid}}`).
+			WithMeta(g.Require("port", "pid")))
+
+	start := time.Now()
+	assertGestaltSuccess(t, suite, []string{"-sdir=" + dir})
+	assert.True(t, time.Since(start) < time.Second*5)
+
+	assertGestaltFails(t,
+		g.Service("crash", g.SH("start", "exit 1")).WaitOutput("never"), []string{})
+
+	assertGestaltFails(t,
+		g.Service("slow", g.SH("start", "sleep 5")).
+			WaitOutput("never").
+			ReadyTimeout(time.Second/10), []string{})
+
+	assertGestaltFails(t, g.Service("other", &otherCmd{g.SH("start", "true")}), []string{})
+}
+
+func TestService_concurrent(t *testing.T) {
+	server := g.Service("server", g.SH("start", "echo ready; sleep 0.3")).WaitOutput("^ready$")
+
+	assertGestaltSuccess(t, g.Parallel("servers").Run(server).Run(server), []string{})
+}
+
+func TestService_hungHTTP(t *testing.T) {
+	donech := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+		<-donech
+	}))
+	defer server.Close()
+	defer close(donech)
+
+	start := time.Now()
+	assertGestaltFails(t,
+		g.Service("server", g.SH("start", "sleep 30")).
+			WaitHTTP(server.URL).
+			ReadyTimeout(time.Second/2), []string{})
+	assert.True(t, time.Since(start) < time.Second*5)
+}
+
+// a Cmd which isn't implemented by the exec package.
+type otherCmd struct {
+	exec.Cmd
+}
+
+func TestRecordReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gestalt-cassette")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cassette := dir + "/cassette.json"
+
+	suite := g.Suite("record").
+		Run(g.SH("produce", "echo run >> {{dir}}/runs; echo a {{dir}} c").
+			FN(g.Capture("_", "b", "_")).
+			WithMeta(g.Export("b"))).
+		Run(g.SH("fail", "echo oops 1>&2; exit 2").
+			ExpectExit(2).
+			StderrContains("oops")).
+		Run(g.Service("server", g.SH("start", "echo listening on 8080; sleep 30")).
+			WaitOutput(`listening on (\d+)`, "port")).
+		Run(g.SH("check", "test {{port}} = 8080").
+			WithMeta(g.Require("port"))).
+		Run(g.Interactive("prompt", "/bin/sh", "-c",
+			"echo run >> {{dir}}/runs; printf 'Name: '; read name; echo hello $name").
+			Expect("Name: ").
+			SendLine("foo").
+			Expect(`hello (\S+)`).
+			Emit("greeted").
+			WithMeta(g.Export("greeted"))).
+		Run(g.SH("greeted", "test {{greeted}} = foo").
+			WithMeta(g.Require("greeted")))
+
+	args := []string{"-sdir=" + dir}
+
+	assertGestaltSuccess(t, suite, append(args, "--record", cassette))
+	assertGestaltSuccess(t, suite, append(args, "--replay", cassette))
+
+	runs, err := ioutil.ReadFile(dir + "/runs")
+	require.NoError(t, err)
+	assert.Equal(t, "run\nrun\n", string(runs))
+
+	assertGestaltFails(t, suite, []string{"-sdir=other", "--replay", cassette})
+
+	failing := g.SH("fail", "exit 1")
+	assertGestaltFails(t, failing, []string{"--record", cassette})
+	assertGestaltFails(t, failing, []string{"--replay", cassette})
+}
+
+func TestUsage(t *testing.T) {
+	suite := g.Suite("usage").
+		Run(g.SH("busy", "i=0; while [ $i -lt 20000 ]; do i=$((i+1)); done").
+			MaxDuration(time.Minute).
+			MaxRSS(1 << 30))
+
+	result, err := gestalt.NewRunner().
+		WithComponent(suite).
+		WithArgs([]string{"eval"}).
+		Execute()
+	require.NoError(t, err)
+	require.True(t, result.Passed())
+
+	for _, p := range result.Paths {
+		if p.Path != "/usage/busy" {
+			assert.Nil(t, p.Usage, p.Path)
+			continue
+		}
+		require.NotNil(t, p.Usage)
+		assert.True(t, p.Usage.UserCPU+p.Usage.SystemCPU > 0)
+		assert.True(t, p.Usage.MaxRSS > 0)
+	}
+
+	assertGestaltFails(t, g.SH("slow", "sleep 0.2").MaxDuration(time.Second/10), []string{})
+	assertGestaltFails(t, g.SH("big", "true").MaxRSS(1), []string{})
+}
+
+func TestUsage_parallel(t *testing.T) {
+	busy := "i=0; while [ $i -lt 20000 ]; do i=$((i+1)); done"
+
+	suite := g.Suite("usage").
+		Run(g.Parallel("p").
+			Run(g.SH("a", busy)).
+			Run(g.SH("b", busy)))
+
+	result, err := gestalt.NewRunner().
+		WithComponent(suite).
+		WithArgs([]string{"eval"}).
+		Execute()
+	require.NoError(t, err)
+	require.True(t, result.Passed())
+
+	usage := make(map[string]*gestalt.Usage)
+	for _, p := range result.Paths {
+		usage[p.Path] = p.Usage
+	}
+	for _, path := range []string{"/usage/p/a", "/usage/p/b"} {
+		if assert.NotNil(t, usage[path], path) {
+			assert.True(t, usage[path].UserCPU+usage[path].SystemCPU > 0, path)
+			assert.True(t, usage[path].MaxRSS > 0, path)
+		}
+	}
+
+	assertGestaltFails(t, g.Parallel("p").
+		Run(g.SH("slow", "sleep 0.2").MaxDuration(time.Second/10)), []string{})
+}
+
+func TestForEach(t *testing.T) {
+	f, err := ioutil.TempFile("", "gestalt-foreach")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	f.Close()
+
+	suite := g.Suite("users").
+		Run(g.SH("list", "printf 'u1 user\\nu2 admin\\nu3 user\\n'").
+			FN(g.Columns("name", "role").GrepField("role", "user").CaptureList("name")).
+			WithMeta(g.Export("name"))).
+		Run(g.ForEach("name", "user").
+			Run(g.SH("delete", "echo deleted {{user}} >> "+f.Name())))
+
+	assertGestaltSuccess(t, suite, []string{})
+
+	buf, err := ioutil.ReadFile(f.Name())
+	require.NoError(t, err)
+	assert.Equal(t, "deleted u1\ndeleted u3\n", string(buf))
+}
+
 func TestCliVars(t *testing.T) {
 	args := []string{
 		"-sa=foo",
@@ -83,6 +459,27 @@ func TestCliVars(t *testing.T) {
 	assertGestaltSuccess(t, consumer(t), args)
 }
 
+func TestCliVarsFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "gestalt-vars")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	_, err = f.WriteString("a=foo\nb=wrong\n")
+	require.NoError(t, err)
+	f.Close()
+
+	os.Setenv("GESTALT_TEST_C", "baz")
+	defer os.Unsetenv("GESTALT_TEST_C")
+
+	args := []string{
+		"--vars-file", f.Name(),
+		"--env-prefix", "GESTALT_TEST_",
+		"-sb=bar",
+	}
+
+	assertGestaltSuccess(t, consumer(t), args)
+}
+
 func TextExpand(t *testing.T) {
 	producer := g.SH("producer", "echo", "{{host}}", "bar", "baz").
 		FN(g.Capture("a", "b", "c")).
@@ -172,10 +569,9 @@ func assertGestaltSuccess(t *testing.T, c gestalt.Component, args []string) {
 }
 
 func assertGestaltFails(t *testing.T, c gestalt.Component, args []string) {
+	failed := false
 	terminate := func(status int) {
-		if status == 0 {
-			t.Fail()
-		}
+		failed = status != 0
 	}
 	args = append([]string{"eval"}, args...)
 
@@ -185,4 +581,8 @@ func assertGestaltFails(t *testing.T, c gestalt.Component, args []string) {
 		WithTerminate(terminate)
 
 	runner.Run()
+
+	if !failed {
+		t.Errorf("gestalt succeeded with %v", args)
+	}
 }
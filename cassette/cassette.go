@@ -0,0 +1,126 @@
+package cassette
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Invocation struct {
+	Path  string   `json:"path"`
+	Args  []string `json:"args"`
+	Dir   string   `json:"dir,omitempty"`
+	Env   []string `json:"env,omitempty"`
+	Stdin string   `json:"stdin,omitempty"`
+
+	Stdout   string        `json:"stdout"`
+	Stderr   string        `json:"stderr"`
+	ExitCode int           `json:"exit-code"`
+	Start    time.Time     `json:"start"`
+	Duration time.Duration `json:"duration"`
+
+	// terminated due to the context being cancelled.
+	Killed bool `json:"killed,omitempty"`
+}
+
+// matches reports whether inv was made with the same input as other.
+func (inv *Invocation) matches(other *Invocation) bool {
+	return inv.Path == other.Path &&
+		reflect.DeepEqual(normalize(inv.Args), normalize(other.Args)) &&
+		inv.Dir == other.Dir &&
+		reflect.DeepEqual(normalize(inv.Env), normalize(other.Env)) &&
+		inv.Stdin == other.Stdin
+}
+
+func (inv *Invocation) String() string {
+	return strings.TrimSpace(inv.Path + " " + strings.Join(inv.Args, " "))
+}
+
+type Cassette struct {
+	Invocations []*Invocation `json:"invocations"`
+
+	replay bool
+	used   []bool
+	mtx    sync.Mutex
+}
+
+// New returns an empty cassette for recording.
+func New() *Cassette {
+	return &Cassette{}
+}
+
+// Load reads a recorded cassette for replay.
+func Load(path string) (*Cassette, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Cassette{replay: true}
+	if err := json.Unmarshal(buf, c); err != nil {
+		return nil, fmt.Errorf("%v: %v", path, err)
+	}
+	c.used = make([]bool, len(c.Invocations))
+	return c, nil
+}
+
+func (c *Cassette) Replaying() bool {
+	return c.replay
+}
+
+func (c *Cassette) Record(inv *Invocation) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.Invocations = append(c.Invocations, inv)
+}
+
+// Match returns the first unused recorded invocation with the same
+// input as inv.
+func (c *Cassette) Match(inv *Invocation) (*Invocation, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	for i, recorded := range c.Invocations {
+		if !c.used[i] && recorded.matches(inv) {
+			c.used[i] = true
+			return recorded, nil
+		}
+	}
+	return nil, fmt.Errorf("no recorded invocation of %v", inv)
+}
+
+func (c *Cassette) Save(path string) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	buf, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(buf, '\n'), 0644)
+}
+
+type contextKey struct{}
+
+func NewContext(ctx context.Context, c *Cassette) context.Context {
+	return context.WithValue(ctx, contextKey{}, c)
+}
+
+// FromContext returns the cassette of ctx, or nil if there is none.
+func FromContext(ctx context.Context) *Cassette {
+	c, _ := ctx.Value(contextKey{}).(*Cassette)
+	return c
+}
+
+// treat nil and empty lists alike.
+func normalize(values []string) []string {
+	if len(values) == 0 {
+		return nil
+	}
+	return values
+}
@@ -0,0 +1,48 @@
+package cassette_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/ovrclk/gestalt/cassette"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCassette(t *testing.T) {
+	f, err := ioutil.TempFile("", "gestalt-cassette")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	f.Close()
+
+	rec := cassette.New()
+	assert.False(t, rec.Replaying())
+
+	rec.Record(&cassette.Invocation{Path: "echo", Args: []string{"a"}, Stdout: "first"})
+	rec.Record(&cassette.Invocation{Path: "echo", Args: []string{"a"}, Stdout: "second"})
+	rec.Record(&cassette.Invocation{Path: "false", ExitCode: 1})
+	require.NoError(t, rec.Save(f.Name()))
+
+	play, err := cassette.Load(f.Name())
+	require.NoError(t, err)
+	assert.True(t, play.Replaying())
+
+	inv, err := play.Match(&cassette.Invocation{Path: "false", Args: []string{}})
+	require.NoError(t, err)
+	assert.Equal(t, 1, inv.ExitCode)
+
+	inv, err = play.Match(&cassette.Invocation{Path: "echo", Args: []string{"a"}})
+	require.NoError(t, err)
+	assert.Equal(t, "first", inv.Stdout)
+
+	inv, err = play.Match(&cassette.Invocation{Path: "echo", Args: []string{"a"}})
+	require.NoError(t, err)
+	assert.Equal(t, "second", inv.Stdout)
+
+	_, err = play.Match(&cassette.Invocation{Path: "echo", Args: []string{"a"}})
+	assert.Error(t, err)
+
+	_, err = play.Match(&cassette.Invocation{Path: "echo", Args: []string{"b"}})
+	assert.Error(t, err)
+}
@@ -2,6 +2,7 @@ package gestalt
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/ovrclk/gestalt/vars"
 )
@@ -21,6 +22,35 @@ type CompositeComponent interface {
 	Children() []Component
 }
 
+// FixtureComponent is implemented by composites whose setup and
+// teardown children must run whenever the composite itself runs.
+type FixtureComponent interface {
+	CompositeComponent
+	Fixtures() []Component
+}
+
+// TimeoutComponent is implemented by components which bound the
+// evaluation of their children with a deadline.
+type TimeoutComponent interface {
+	Component
+	Timeout() time.Duration
+}
+
+// EnvComponent is implemented by components which provide default
+// environment variables ("KEY=value") to the commands they contain.
+type EnvComponent interface {
+	Component
+	Env() []string
+}
+
+// SuffixComponent is implemented by pass-through components whose
+// descendants' path segments carry a suffix, such as the index of a
+// ForEach iteration.
+type SuffixComponent interface {
+	Component
+	PathSuffix() string
+}
+
 type component struct {
 	name   string
 	action Action
@@ -70,6 +70,17 @@ func (c *ensure) Children() []gestalt.Component {
 	return children
 }
 
+func (c *ensure) Fixtures() []gestalt.Component {
+	fixtures := make([]gestalt.Component, 0)
+	if c.pre != nil {
+		fixtures = append(fixtures, c.pre)
+	}
+	if c.post != nil {
+		fixtures = append(fixtures, c.post)
+	}
+	return fixtures
+}
+
 func (c *ensure) Eval(e gestalt.Evaluator) error {
 	if c.pre != nil {
 		e.Evaluate(c.pre)
@@ -0,0 +1,128 @@
+package component
+
+import (
+	"fmt"
+
+	"github.com/ovrclk/gestalt"
+	"github.com/ovrclk/gestalt/vars"
+)
+
+type forEach struct {
+	cmp     gestalt.Component
+	child   gestalt.Component
+	listVar string
+	itemVar string
+}
+
+// NewForEach evaluates its child once for each element of the list
+// stored in listVar (see vars.PutList), with the element bound to
+// itemVar.  The path segments contributed by the child carry the
+// index: /child[0], /child[1]...
+func NewForEach(listVar, itemVar string) *forEach {
+	c := &forEach{
+		cmp:     gestalt.NewComponent("foreach", nil),
+		listVar: listVar,
+		itemVar: itemVar,
+	}
+	// the default lets the child require itemVar.
+	c.cmp.WithMeta(vars.NewMeta().Require(listVar).Default(itemVar, ""))
+	return c
+}
+
+func (c *forEach) Eval(e gestalt.Evaluator) error {
+	items, err := vars.GetList(e.Vars(), c.listVar)
+	if err != nil {
+		return err
+	}
+
+	for i, item := range items {
+		e.Vars().Put(c.itemVar, item)
+		e.Evaluate(&iteration{c.child, i})
+		if e.HasError() {
+			break
+		}
+	}
+
+	return nil
+}
+
+func (c *forEach) IsPassThrough() bool {
+	return true
+}
+
+func (c *forEach) Name() string {
+	return fmt.Sprintf("%v.%v", c.Child().Name(), c.cmp.Name())
+}
+
+// the item variable is provided to the child, not required of the
+// enclosing component.
+func (c *forEach) Meta() vars.Meta {
+	child := c.child.Meta()
+
+	m := vars.NewMeta().Merge(c.cmp.Meta())
+	for _, k := range child.Requires() {
+		if k != c.itemVar {
+			m.Require(k)
+		}
+	}
+	m.Export(child.Exports()...)
+	for k, v := range child.Defaults() {
+		m.Default(k, v)
+	}
+	return m
+}
+
+func (c *forEach) WithMeta(m vars.Meta) gestalt.Component {
+	c.cmp.WithMeta(m)
+	return c
+}
+
+func (c *forEach) Children() []gestalt.Component {
+	return []gestalt.Component{c.Child()}
+}
+
+func (c *forEach) Child() gestalt.Component {
+	return c.child
+}
+
+func (c *forEach) Run(child gestalt.Component) gestalt.Component {
+	c.child = child
+	return c
+}
+
+// iteration evaluates the child of a forEach, suffixing the path
+// segments it contributes with the index.
+type iteration struct {
+	child gestalt.Component
+	index int
+}
+
+func (c *iteration) Name() string {
+	return c.child.Name() + ".item"
+}
+
+func (c *iteration) PathSuffix() string {
+	return fmt.Sprintf("[%v]", c.index)
+}
+
+func (c *iteration) IsPassThrough() bool {
+	return true
+}
+
+func (c *iteration) Children() []gestalt.Component {
+	return []gestalt.Component{c.child}
+}
+
+// passes the child's exports on to the forEach.
+func (c *iteration) Meta() vars.Meta {
+	return c.child.Meta()
+}
+
+func (c *iteration) WithMeta(_ vars.Meta) gestalt.Component {
+	return c
+}
+
+func (c *iteration) Eval(e gestalt.Evaluator) error {
+	e.Evaluate(c.child)
+	return nil
+}
@@ -0,0 +1,80 @@
+package component_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ovrclk/gestalt"
+	"github.com/ovrclk/gestalt/component"
+	"github.com/ovrclk/gestalt/vars"
+)
+
+func TestForEach(t *testing.T) {
+	var paths, items []string
+
+	visit := gestalt.NewComponent("visit", func(e gestalt.Evaluator) error {
+		paths = append(paths, e.Path())
+		items = append(items, e.Vars().Get("user"))
+		return nil
+	}).WithMeta(vars.NewMeta().Require("user"))
+
+	list := gestalt.NewComponent("list", func(e gestalt.Evaluator) error {
+		vars.PutList(e.Vars(), "users", []string{"u1", "u2", "u3"})
+		return nil
+	}).WithMeta(vars.NewMeta().Export("users"))
+
+	cmp := component.NewGroup("test").
+		Run(list).
+		Run(component.NewForEach("users", "user").Run(visit))
+
+	assert.Empty(t, gestalt.Validate(cmp))
+
+	assert.NoError(t, gestalt.NewEvaluator().Evaluate(cmp))
+	assert.Equal(t, []string{"/test/visit[0]", "/test/visit[1]", "/test/visit[2]"}, paths)
+	assert.Equal(t, []string{"u1", "u2", "u3"}, items)
+}
+
+func TestForEach_passThrough(t *testing.T) {
+	var paths []string
+
+	visit := gestalt.NewComponent("visit", func(e gestalt.Evaluator) error {
+		paths = append(paths, e.Path())
+		e.Vars().Put("last", e.Vars().Get("item"))
+		return nil
+	}).WithMeta(vars.NewMeta().Require("item").Export("last"))
+
+	cmp := component.NewForEach("list", "item").
+		Run(component.NewRetry(2, 0).Run(visit))
+
+	e := gestalt.NewEvaluator()
+	vars.PutList(e.Vars(), "list", []string{"a", "b"})
+
+	assert.NoError(t, e.Evaluate(cmp))
+	assert.Equal(t, []string{"/visit[0]", "/visit[1]"}, paths)
+	assert.Equal(t, "b", e.Vars().Get("last"))
+}
+
+func TestForEach_errors(t *testing.T) {
+	count := 0
+	failing := gestalt.NewComponent("failing", func(e gestalt.Evaluator) error {
+		count++
+		return errComponent(e.Vars().Get("item")).Eval(e)
+	})
+
+	{
+		e := gestalt.NewEvaluator()
+		vars.PutList(e.Vars(), "list", []string{"a", "b"})
+		e.Evaluate(component.NewForEach("list", "item").Run(failing))
+		assert.Equal(t, 1, count)
+		if assert.Len(t, e.Errors(), 1) {
+			assert.Contains(t, e.Errors()[0].Error(), "/failing[0]")
+		}
+	}
+
+	{
+		e := gestalt.NewEvaluator()
+		res := e.Evaluate(component.NewForEach("missing", "item").Run(failing))
+		assert.EqualError(t, res, "list missing not set")
+	}
+}
@@ -8,6 +8,7 @@ import (
 type Group interface {
 	gestalt.CompositeComponent
 	Run(gestalt.Component) Group
+	AddEnv(string, string) Group
 }
 
 /* group component */
@@ -15,6 +16,7 @@ type group struct {
 	cmp      gestalt.Component
 	terminal bool
 	children []gestalt.Component
+	env      []string
 }
 
 func NewSuite(name string) *group {
@@ -56,6 +58,17 @@ func (c *group) Run(child gestalt.Component) Group {
 	return c
 }
 
+// AddEnv sets an environment variable for all commands in the group.
+// Unlike exec.Cmd, the key is used as given.
+func (c *group) AddEnv(k, v string) Group {
+	c.env = append(c.env, k+"="+v)
+	return c
+}
+
+func (c *group) Env() []string {
+	return c.env
+}
+
 func (c *group) Eval(e gestalt.Evaluator) error {
 
 	// evaluate children up to an error
@@ -1,11 +1,13 @@
 package component_test
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/ovrclk/gestalt"
 	"github.com/ovrclk/gestalt/component"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestGroup(t *testing.T) {
@@ -65,3 +67,33 @@ func TestSuite(t *testing.T) {
 
 	e.Wait()
 }
+
+func TestSuite_bgFailure(t *testing.T) {
+	ran := false
+
+	cmp := component.NewSuite("server")
+	cmp.Run(component.NewBG().
+		Run(gestalt.NewComponent("crash", func(e gestalt.Evaluator) error {
+			return fmt.Errorf("crashed")
+		})))
+
+	cmp.Run(gestalt.NewComponent("check", func(e gestalt.Evaluator) error {
+		ran = true
+		return nil
+	}))
+
+	e := gestalt.NewEvaluator()
+	res := e.Evaluate(cmp)
+
+	assert.NoError(t, res)
+	assert.True(t, ran)
+	assert.True(t, e.HasError())
+
+	require.Len(t, e.Errors(), 1)
+	err, ok := e.Errors()[0].(gestalt.Error)
+	require.True(t, ok)
+	assert.Equal(t, "/server/crash", err.Path())
+
+	e.Wait()
+	assert.Len(t, e.Errors(), 1)
+}
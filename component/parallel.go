@@ -0,0 +1,132 @@
+package component
+
+import (
+	"github.com/ovrclk/gestalt"
+	"github.com/ovrclk/gestalt/vars"
+)
+
+type Parallel interface {
+	gestalt.CompositeComponent
+	Run(gestalt.Component) Parallel
+	MaxConcurrency(int) Parallel
+}
+
+/* parallel component */
+type parallel struct {
+	cmp      gestalt.Component
+	limit    int
+	children []gestalt.Component
+}
+
+func NewParallel(name string) *parallel {
+	return &parallel{
+		cmp: gestalt.NewComponent(name, nil),
+	}
+}
+
+func (c *parallel) Children() []gestalt.Component {
+	return c.children
+}
+
+func (c *parallel) Name() string {
+	return c.cmp.Name()
+}
+
+func (c *parallel) Meta() vars.Meta {
+	return c.cmp.Meta()
+}
+
+func (c *parallel) WithMeta(m vars.Meta) gestalt.Component {
+	c.cmp.WithMeta(m)
+	return c
+}
+
+func (c *parallel) IsPassThrough() bool {
+	return false
+}
+
+func (c *parallel) Run(child gestalt.Component) Parallel {
+	c.children = append(c.children, child)
+	return c
+}
+
+func (c *parallel) MaxConcurrency(limit int) Parallel {
+	c.limit = limit
+	return c
+}
+
+func (c *parallel) Eval(e gestalt.Evaluator) error {
+	limit := c.limit
+	if limit <= 0 || limit > len(c.children) {
+		limit = len(c.children)
+	}
+
+	sem := make(chan struct{}, limit)
+	exports := make([]vars.Vars, len(c.children))
+
+	for i, child := range c.children {
+		e.Fork(&parallelChild{child, sem, &exports[i]})
+	}
+
+	// join children; their errors are added to this component.
+	e.Wait()
+
+	// apply exports in child order.
+	for _, exported := range exports {
+		if exported != nil {
+			e.Vars().Merge(exported)
+		}
+	}
+
+	return nil
+}
+
+// parallelChild evaluates the wrapped child within a concurrency
+// slot and collects its exports.  Children skipped by the evaluator
+// never take a slot.
+type parallelChild struct {
+	child   gestalt.Component
+	sem     chan struct{}
+	exports *vars.Vars
+}
+
+func (c *parallelChild) Name() string {
+	return c.child.Name() + ".parallel"
+}
+
+func (c *parallelChild) Children() []gestalt.Component {
+	return []gestalt.Component{c.child}
+}
+
+func (c *parallelChild) IsPassThrough() bool {
+	return true
+}
+
+func (c *parallelChild) Meta() vars.Meta {
+	return vars.NewMeta()
+}
+
+func (c *parallelChild) WithMeta(_ vars.Meta) gestalt.Component {
+	return c
+}
+
+func (c *parallelChild) Eval(e gestalt.Evaluator) error {
+	select {
+	case c.sem <- struct{}{}:
+	case <-e.Context().Done():
+		return nil
+	}
+	defer func() { <-c.sem }()
+
+	e.Evaluate(c.child)
+
+	exported := vars.NewVars()
+	for _, k := range c.child.Meta().Exports() {
+		if e.Vars().Has(k) {
+			exported.Put(k, e.Vars().Get(k))
+		}
+	}
+	*c.exports = exported
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package component_test
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ovrclk/gestalt"
+	"github.com/ovrclk/gestalt/component"
+	"github.com/ovrclk/gestalt/vars"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParallel(t *testing.T) {
+	var wg sync.WaitGroup
+	wg.Add(3)
+
+	donech := make(chan interface{})
+	go func() {
+		wg.Wait()
+		close(donech)
+	}()
+
+	cmp := component.NewParallel("users")
+	for _, name := range []string{"a", "b", "c"} {
+		name := name
+		cmp.Run(gestalt.NewComponent(name, func(e gestalt.Evaluator) error {
+			assert.Equal(t, "/users/"+name, e.Path())
+			wg.Done()
+			select {
+			case <-donech:
+			case <-time.After(time.Second):
+				return fmt.Errorf("children not run concurrently")
+			}
+			e.Emit(name, "created")
+			return nil
+		}).WithMeta(vars.NewMeta().Export(name)))
+	}
+
+	cmp.WithMeta(vars.NewMeta().Export("a", "b", "c"))
+
+	e := gestalt.NewEvaluator()
+	assert.NoError(t, e.Evaluate(cmp))
+	assert.Empty(t, e.Errors())
+
+	for _, name := range []string{"a", "b", "c"} {
+		assert.Equal(t, "created", e.Vars().Get(name))
+	}
+}
+
+func TestParallel_maxConcurrency(t *testing.T) {
+	var running, peak int32
+
+	cmp := component.NewParallel("users").MaxConcurrency(2)
+	for i := 0; i < 6; i++ {
+		cmp.Run(gestalt.NewComponent(fmt.Sprintf("user-%v", i), func(e gestalt.Evaluator) error {
+			cur := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			for {
+				prev := atomic.LoadInt32(&peak)
+				if cur <= prev || atomic.CompareAndSwapInt32(&peak, prev, cur) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond * 10)
+			return nil
+		}))
+	}
+
+	e := gestalt.NewEvaluator()
+	assert.NoError(t, e.Evaluate(cmp))
+	assert.Empty(t, e.Errors())
+	assert.Equal(t, int32(2), peak)
+}
+
+func TestParallel_errors(t *testing.T) {
+	cmp := component.NewParallel("users").
+		Run(gestalt.NewComponent("a", func(e gestalt.Evaluator) error {
+			return fmt.Errorf("failed a")
+		})).
+		Run(gestalt.NoopComponent("b")).
+		Run(gestalt.NewComponent("c", func(e gestalt.Evaluator) error {
+			return fmt.Errorf("failed c")
+		}))
+
+	e := gestalt.NewEvaluator()
+	assert.NoError(t, e.Evaluate(cmp))
+	require.Len(t, e.Errors(), 2)
+
+	paths := make([]string, 0)
+	for _, err := range e.Errors() {
+		if err, ok := err.(gestalt.Error); ok {
+			paths = append(paths, err.Path())
+		}
+	}
+	sort.Strings(paths)
+	assert.Equal(t, []string{"/users/a", "/users/c"}, paths)
+}
@@ -19,6 +19,7 @@ type wrap struct {
 	cmp     gestalt.Component
 	wrapper WrapFn
 	child   gestalt.Component
+	timeout time.Duration
 }
 
 type WrapFn func(Wrap, gestalt.Evaluator) error
@@ -69,10 +70,29 @@ func NewIgnore() *wrap {
 	})
 }
 
+// NewTimeout cancels its child, along with anything started under it
+// (including background components), once timeout elapses.
+func NewTimeout(timeout time.Duration) *wrap {
+	c := NewWrap("timeout", func(c Wrap, e gestalt.Evaluator) error {
+		e.Evaluate(c.Child())
+
+		if gestalt.TimedOut(e.Context()) {
+			return fmt.Errorf("timed out after %v", timeout)
+		}
+		return nil
+	})
+	c.timeout = timeout
+	return c
+}
+
 func (c *wrap) Eval(e gestalt.Evaluator) error {
 	return c.wrapper(c, e)
 }
 
+func (c *wrap) Timeout() time.Duration {
+	return c.timeout
+}
+
 func (c *wrap) IsPassThrough() bool {
 	return true
 }
@@ -60,6 +60,45 @@ func TestBG(t *testing.T) {
 	e.Wait()
 }
 
+func TestTimeout(t *testing.T) {
+	server := gestalt.NewComponent("server", func(e gestalt.Evaluator) error {
+		select {
+		case <-e.Context().Done():
+			return nil
+		case <-time.After(time.Second):
+			assert.Fail(t, "context deadline never reached")
+			return nil
+		}
+	})
+
+	{
+		e := gestalt.NewEvaluator()
+		res := e.Evaluate(component.NewTimeout(time.Millisecond * 10).Run(server))
+		assert.EqualError(t, res, "timed out after 10ms")
+		assert.NotEmpty(t, e.Errors())
+		assert.NoError(t, e.Context().Err())
+	}
+
+	{
+		// only the wrap whose deadline passed reports it.
+		e := gestalt.NewEvaluator()
+		res := e.Evaluate(component.NewTimeout(time.Millisecond * 10).
+			Run(component.NewTimeout(time.Second * 10).Run(server)))
+		assert.EqualError(t, res, "timed out after 10ms")
+		if assert.Len(t, e.Errors(), 1) {
+			assert.NotContains(t, e.Errors()[0].Error(), "10s")
+		}
+	}
+
+	{
+		cmp := component.NewTimeout(time.Second).
+			Run(gestalt.NoopComponent("quick"))
+		e := gestalt.NewEvaluator()
+		assert.NoError(t, e.Evaluate(cmp))
+		assert.Empty(t, e.Errors())
+	}
+}
+
 func TestIgnore(t *testing.T) {
 	ran := false
 	cmp := component.NewGroup("test").
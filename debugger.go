@@ -211,7 +211,7 @@ func (h *debugHandler) shouldBreak(path string, points []string, prefix string)
 	if h.quitting {
 		return false
 	}
-	if idx := h.matchPath(path, points); idx >= 0 {
+	if idx := matchPath(path, points); idx >= 0 {
 		fmt := "\n%vpoint %v at %v\n"
 		color.New(color.FgYellow).Fprintf(h.out, fmt, prefix, idx, path)
 		return true
@@ -219,7 +219,7 @@ func (h *debugHandler) shouldBreak(path string, points []string, prefix string)
 	return false
 }
 
-func (h *debugHandler) matchPath(path string, points []string) int {
+func matchPath(path string, points []string) int {
 	for idx, point := range points {
 		if strings.HasSuffix(path, point) {
 			return idx
@@ -470,14 +470,14 @@ func (h *debugHandler) listComponents(e Evaluator, node Component) {
 			fmt.Fprintf(h.out, " ")
 		}
 
-		if idx := h.matchPath(path, h.breakpoints); idx >= 0 {
+		if idx := matchPath(path, h.breakpoints); idx >= 0 {
 			highlight = true
 			color.New(color.FgYellow).Fprintf(h.out, "*")
 		} else {
 			fmt.Fprintf(h.out, " ")
 		}
 
-		if idx := h.matchPath(path, h.failpoints); idx >= 0 {
+		if idx := matchPath(path, h.failpoints); idx >= 0 {
 			highlight = true
 			color.New(color.FgRed).Fprintf(h.out, "*")
 		} else {
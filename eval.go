@@ -40,16 +40,50 @@ type evaluator struct {
 	ctx  *ctxVisitor
 	err  *errVisitor
 	wait *waitVisitor
+	skip *skipVisitor
+
+	usage *usageVisitor
 
 	visitors []Visitor
 
 	handler evalHandler
+
+	// cancel enclosing scope as soon as a forked component fails.
+	failFast bool
 }
 
 type evalHandler interface {
 	Eval(Evaluator, Component) error
 }
 
+// forkHandler is implemented by handlers which also apply to the
+// components evaluated by forks.
+type forkHandler interface {
+	evalHandler
+	fork() evalHandler
+}
+
+// forkVisitor is implemented by visitors which also observe the
+// components evaluated by forks.  The visitor returned by Fork shares
+// its results with the original and may be used concurrently with it.
+type forkVisitor interface {
+	Visitor
+	Fork() Visitor
+}
+
+// pathTracker is implemented by evaluators which expose the state of
+// their path visitor.
+type pathTracker interface {
+	paths() *pathVisitor
+}
+
+// skipper is implemented by evaluators which can mark the
+// current component as skipped.
+type skipper interface {
+	Skip()
+	Skipped() bool
+}
+
 func NewEvaluator(visitors ...Visitor) *evaluator {
 	return NewEvaluatorWithLogger(newLogBuilder().Logger(), visitors...)
 }
@@ -63,6 +97,8 @@ func NewEvaluatorWithLogger(logger Logger, visitors ...Visitor) *evaluator {
 		ctx:      newCtxVisitor(),
 		err:      newErrVisitor(),
 		wait:     newWaitVisitor(),
+		skip:     newSkipVisitor(),
+		usage:    newUsageVisitor(),
 		visitors: visitors,
 		handler:  defaultEvalHandler,
 	}
@@ -101,11 +137,16 @@ func (e *evaluator) Stop() {
 }
 
 func (e *evaluator) Wait() {
-	e.wait.Wait()
+	for _, err := range e.wait.Wait() {
+		e.err.Add(err)
+	}
 }
 
 func (e *evaluator) HasError() bool {
-	return len(e.err.Current()) > 0
+	if len(e.err.Current()) > 0 {
+		return true
+	}
+	return e.failFast && e.wait.Failed()
 }
 
 func (e *evaluator) ClearError() {
@@ -116,6 +157,22 @@ func (e *evaluator) Errors() []error {
 	return e.err.Current()
 }
 
+func (e *evaluator) Skip() {
+	e.skip.Skip()
+}
+
+func (e *evaluator) Skipped() bool {
+	return e.skip.Current()
+}
+
+func (e *evaluator) paths() *pathVisitor {
+	return e.path
+}
+
+func (e *evaluator) RecordUsage(u Usage) {
+	e.usage.Add(u)
+}
+
 func (e *evaluator) Evaluate(node Component) error {
 	e.push(node)
 
@@ -137,6 +194,8 @@ func (e *evaluator) push(node Component) {
 	e.vars.Push(e, node)
 	e.err.Push(e, node)
 	e.wait.Push(e, node)
+	e.skip.Push(e, node)
+	e.usage.Push(e, node)
 
 	for _, v := range e.visitors {
 		v.Push(e, node)
@@ -149,6 +208,8 @@ func (e *evaluator) pop(node Component) {
 		e.visitors[i].Pop(e, node)
 	}
 
+	e.usage.Pop(e, node)
+	e.skip.Pop(e, node)
 	e.wait.Pop(e, node)
 	e.err.Pop(e, node)
 	e.vars.Pop(e, node)
@@ -164,12 +225,20 @@ func (e *evaluator) addError(err error) {
 }
 
 func (e *evaluator) Fork(node Component) error {
-	wg := e.wait.Current()
-	wg.Add(1)
+	forks := e.wait.Current()
+	cancel := e.ctx.ParentCancel()
+	forks.Add(1)
 	go func(child Evaluator) {
-		defer wg.Done()
+		defer forks.Done()
 		child.Evaluate(node)
 		child.Wait()
+
+		if errs := child.Errors(); len(errs) > 0 {
+			forks.AddErrors(errs)
+			if e.failFast {
+				cancel()
+			}
+		}
 	}(e.forkFor(node))
 	return nil
 }
@@ -180,15 +249,36 @@ func (e *evaluator) Root() Component {
 
 func (e *evaluator) forkFor(node Component) *evaluator {
 	return &evaluator{
-		node:    e.node.Clone(),
-		path:    e.path.Clone(),
-		log:     e.log.Clone(),
-		vars:    e.vars.Clone(),
-		ctx:     e.ctx.Clone(),
-		err:     e.err.Clone(),
-		wait:    e.wait.Clone(),
-		handler: defaultEvalHandler,
+		node:     e.node.Clone(),
+		path:     e.path.Clone(),
+		log:      e.log.Clone(),
+		vars:     e.vars.Clone(),
+		ctx:      e.ctx.Clone(),
+		err:      e.err.Clone(),
+		wait:     e.wait.Clone(),
+		skip:     e.skip.Clone(),
+		usage:    e.usage.Clone(),
+		visitors: forkVisitors(e.visitors),
+		handler:  forkEvalHandler(e.handler),
+		failFast: e.failFast,
+	}
+}
+
+func forkVisitors(visitors []Visitor) []Visitor {
+	var forks []Visitor
+	for _, v := range visitors {
+		if fv, ok := v.(forkVisitor); ok {
+			forks = append(forks, fv.Fork())
+		}
+	}
+	return forks
+}
+
+func forkEvalHandler(h evalHandler) evalHandler {
+	if fh, ok := h.(forkHandler); ok {
+		return fh.fork()
 	}
+	return defaultEvalHandler
 }
 
 type _defaultEvalHandler struct{}
@@ -0,0 +1,160 @@
+package gestalt
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ovrclk/gestalt/vars"
+)
+
+const (
+	eventPush = "push"
+	eventPop  = "pop"
+)
+
+type event struct {
+	Type    string       `json:"type"`
+	Path    string       `json:"path"`
+	Name    string       `json:"name"`
+	Time    time.Time    `json:"time"`
+	Start   *time.Time   `json:"start,omitempty"`
+	Elapsed *float64     `json:"elapsed,omitempty"`
+	Outcome Status       `json:"outcome,omitempty"`
+	Errors  []eventError `json:"errors,omitempty"`
+	Vars    *varsDiff    `json:"vars,omitempty"`
+}
+
+type eventError struct {
+	Path    string `json:"path,omitempty"`
+	Message string `json:"message"`
+	Detail  string `json:"detail,omitempty"`
+}
+
+type varsDiff struct {
+	Set   map[string]string `json:"set,omitempty"`
+	Unset []string          `json:"unset,omitempty"`
+}
+
+type eventState struct {
+	start time.Time
+	vars  vars.Vars
+}
+
+// eventVisitor writes a JSON object per line for every push and pop.
+type eventVisitor struct {
+	enc   *eventEncoder
+	stack []eventState
+}
+
+// encoder shared by an eventVisitor and its forks.
+type eventEncoder struct {
+	mtx sync.Mutex
+	enc *json.Encoder
+}
+
+func (e *eventEncoder) Encode(ev *event) {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	e.enc.Encode(ev)
+}
+
+func newEventVisitor(out io.Writer) *eventVisitor {
+	return &eventVisitor{enc: &eventEncoder{enc: json.NewEncoder(out)}}
+}
+
+func (h *eventVisitor) Fork() Visitor {
+	return &eventVisitor{enc: h.enc}
+}
+
+func (h *eventVisitor) Push(t Traverser, node Component) {
+	state := eventState{start: time.Now()}
+
+	if e, ok := t.(Evaluator); ok {
+		state.vars = e.Vars().Clone()
+	}
+
+	h.stack = append(h.stack, state)
+
+	h.enc.Encode(&event{
+		Type: eventPush,
+		Path: t.Path(),
+		Name: node.Name(),
+		Time: state.start,
+	})
+}
+
+func (h *eventVisitor) Pop(t Traverser, node Component) {
+	topidx := len(h.stack) - 1
+	if topidx < 0 {
+		return
+	}
+
+	state := h.stack[topidx]
+	h.stack = h.stack[0:topidx]
+
+	now := time.Now()
+	elapsed := now.Sub(state.start).Seconds()
+
+	ev := &event{
+		Type:    eventPop,
+		Path:    t.Path(),
+		Name:    node.Name(),
+		Time:    now,
+		Start:   &state.start,
+		Elapsed: &elapsed,
+		Outcome: statusOf(t),
+	}
+
+	if e, ok := t.(Evaluator); ok {
+		for _, err := range e.Errors() {
+			ev.Errors = append(ev.Errors, newEventError(err))
+		}
+		ev.Vars = diffVars(state.vars, e.Vars())
+	}
+
+	h.enc.Encode(ev)
+}
+
+func newEventError(err error) eventError {
+	ev := eventError{Message: err.Error()}
+	if errd, ok := err.(ErrorWithDetail); ok {
+		ev.Detail = errd.Detail()
+	}
+	if errp, ok := err.(Error); ok {
+		ev.Path = errp.Path()
+	}
+	return ev
+}
+
+func diffVars(before vars.Vars, after vars.Vars) *varsDiff {
+	if before == nil {
+		before = vars.NewVars()
+	}
+
+	diff := &varsDiff{}
+
+	for _, k := range after.Keys() {
+		if v := after.Get(k); !before.Has(k) || before.Get(k) != v {
+			if diff.Set == nil {
+				diff.Set = make(map[string]string)
+			}
+			diff.Set[k] = v
+		}
+	}
+
+	for _, k := range before.Keys() {
+		if !after.Has(k) {
+			diff.Unset = append(diff.Unset, k)
+		}
+	}
+
+	if diff.Set == nil && diff.Unset == nil {
+		return nil
+	}
+
+	sort.Strings(diff.Unset)
+	return diff
+}
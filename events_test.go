@@ -0,0 +1,120 @@
+package gestalt_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/ovrclk/gestalt"
+	"github.com/ovrclk/gestalt/component"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testEvent struct {
+	Type    string `json:"type"`
+	Path    string `json:"path"`
+	Name    string `json:"name"`
+	Outcome string `json:"outcome"`
+	Errors  []struct {
+		Path    string `json:"path"`
+		Message string `json:"message"`
+	} `json:"errors"`
+	Vars *struct {
+		Set map[string]string `json:"set"`
+	} `json:"vars"`
+}
+
+func TestEvents(t *testing.T) {
+	f, err := ioutil.TempFile("", "gestalt-events")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	cmp := component.NewSuite("top").
+		Run(exportComponent("a", "foo")).
+		Run(gestalt.NewComponent("fail", func(_ gestalt.Evaluator) error {
+			return fmt.Errorf("failed")
+		}))
+
+	gestalt.NewRunner().
+		WithComponent(cmp).
+		WithArgs([]string{"eval", "--events", f.Name()}).
+		WithTerminate(func(int) {}).
+		Run()
+
+	events := make([]testEvent, 0)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		ev := testEvent{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &ev))
+		events = append(events, ev)
+	}
+	require.NoError(t, scanner.Err())
+
+	require.Len(t, events, 6)
+
+	expected := []struct{ typ, path string }{
+		{"push", "/top"},
+		{"push", "/top/create"},
+		{"pop", "/top/create"},
+		{"push", "/top/fail"},
+		{"pop", "/top/fail"},
+		{"pop", "/top"},
+	}
+	for i, ex := range expected {
+		assert.Equal(t, ex.typ, events[i].Type)
+		assert.Equal(t, ex.path, events[i].Path)
+	}
+
+	create := events[2]
+	assert.Equal(t, "pass", create.Outcome)
+	require.NotNil(t, create.Vars)
+	assert.Equal(t, map[string]string{"a": "foo"}, create.Vars.Set)
+
+	fail := events[4]
+	assert.Equal(t, "fail", fail.Outcome)
+	require.Len(t, fail.Errors, 1)
+	assert.Equal(t, "/top/fail", fail.Errors[0].Path)
+	assert.Equal(t, "/top/fail: failed", fail.Errors[0].Message)
+
+	top := events[5]
+	assert.Equal(t, "fail", top.Outcome)
+	require.NotNil(t, top.Vars)
+	assert.Equal(t, "foo", top.Vars.Set["a"])
+}
+
+func TestEvents_parallel(t *testing.T) {
+	f, err := ioutil.TempFile("", "gestalt-events")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	cmp := component.NewParallel("p").
+		Run(gestalt.NoopComponent("a")).
+		Run(gestalt.NoopComponent("b"))
+
+	gestalt.NewRunner().
+		WithComponent(cmp).
+		WithArgs([]string{"eval", "--events", f.Name()}).
+		WithTerminate(func(int) {}).
+		Run()
+
+	pops := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		ev := testEvent{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &ev))
+		if ev.Type == "pop" {
+			pops[ev.Path] = ev.Outcome
+		}
+	}
+	require.NoError(t, scanner.Err())
+
+	assert.Equal(t, "pass", pops["/p/a"])
+	assert.Equal(t, "pass", pops["/p/b"])
+	assert.Equal(t, "pass", pops["/p"])
+}
@@ -9,9 +9,9 @@ import (
 func DevServer() gestalt.Component {
 	return g.Group("dev-server").
 		Run(g.SH("cleanup", "echo", "cleanup")).
-		Run(g.BG().
-			Run(g.SH("start", "while true; do echo .; sleep 1; done"))).
-		Run(g.SH("wait", "sleep", "10")).
+		Run(g.Service("server",
+			g.SH("start", "while true; do echo .; sleep 1; done")).
+			WaitOutput(`^\.$`)).
 		Run(g.Retry(5).
 			Run(g.SH("check", "echo", "check")))
 }
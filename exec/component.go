@@ -5,22 +5,60 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"os"
 	"os/exec"
+	"regexp"
 	"strings"
 	"sync"
-	"syscall"
+	"time"
 
 	"github.com/ovrclk/gestalt"
+	"github.com/ovrclk/gestalt/cassette"
 	"github.com/ovrclk/gestalt/vars"
 )
 
 type CmdFn func(*bufio.Reader, gestalt.Evaluator) error
 
+type stderrCheck func(vars.Vars, string) error
+
+type envMode int
+
+const (
+	// inherit the environment of this process, with overrides.
+	envInherit envMode = iota
+	// start with an empty environment.
+	envClean
+	// inherit only the allowed variables.
+	envAllowlist
+)
+
+type stdinFn func(vars.Vars) (string, error)
+
 type Cmd interface {
 	gestalt.Component
 	FN(CmdFn) Cmd
 	Dir(string) Cmd
 	AddEnv(string, string) Cmd
+	UpperEnv(bool) Cmd
+	InheritEnv() Cmd
+	CleanEnv() Cmd
+	AllowEnv(...string) Cmd
+	StreamOutput(bool) Cmd
+
+	ExpectExit(...int) Cmd
+	ExpectFailure() Cmd
+	StderrContains(string) Cmd
+	StderrMatches(string) Cmd
+
+	Stdin(string) Cmd
+	StdinFile(string) Cmd
+	StdinFrom(string) Cmd
+
+	KillGrace(time.Duration) Cmd
+
+	MaxDuration(time.Duration) Cmd
+	MaxRSS(int64) Cmd
 }
 
 type cmd struct {
@@ -28,16 +66,35 @@ type cmd struct {
 	path string
 	dir  string
 	args []string
-	env  []string
+	env  [][2]string
+
+	envMode  envMode
+	envAllow []string
+	rawEnv   bool
+
+	// nil: use the logger's setting
+	stream *bool
+
+	exitCodes     []int
+	expectFailure bool
+	stderrChecks  []stderrCheck
+
+	stdin stdinFn
+
+	grace time.Duration
+
+	maxDuration time.Duration
+	maxRSS      int64
 
 	fn CmdFn
 }
 
 func NewCmd(name string, path string, args []string) Cmd {
 	return &cmd{
-		cmp:  gestalt.NewComponent(name, nil),
-		path: path,
-		args: args,
+		cmp:   gestalt.NewComponent(name, nil),
+		path:  path,
+		args:  args,
+		grace: DefaultKillGrace,
 	}
 }
 
@@ -68,104 +125,469 @@ func (c *cmd) Dir(dir string) Cmd {
 	return c
 }
 
+// AddEnv sets an environment variable for the command.  The key is
+// upper-cased unless disabled with UpperEnv(false).
 func (c *cmd) AddEnv(k, v string) Cmd {
-	c.env = append(c.env, strings.ToUpper(k)+"="+v)
+	c.env = append(c.env, [2]string{k, v})
+	return c
+}
+
+func (c *cmd) UpperEnv(upper bool) Cmd {
+	c.rawEnv = !upper
+	return c
+}
+
+func (c *cmd) InheritEnv() Cmd {
+	c.envMode = envInherit
+	return c
+}
+
+func (c *cmd) CleanEnv() Cmd {
+	c.envMode = envClean
+	return c
+}
+
+func (c *cmd) AllowEnv(keys ...string) Cmd {
+	c.envMode = envAllowlist
+	c.envAllow = append(c.envAllow, keys...)
+	return c
+}
+
+func (c *cmd) StreamOutput(stream bool) Cmd {
+	c.stream = &stream
+	return c
+}
+
+func (c *cmd) ExpectExit(codes ...int) Cmd {
+	c.exitCodes = append(c.exitCodes, codes...)
+	return c
+}
+
+func (c *cmd) ExpectFailure() Cmd {
+	c.expectFailure = true
+	return c
+}
+
+func (c *cmd) StderrContains(value string) Cmd {
+	c.stderrChecks = append(c.stderrChecks, func(v vars.Vars, stderr string) error {
+		value := vars.Expand(v, value)
+		if !strings.Contains(stderr, value) {
+			return fmt.Errorf("stderr does not contain %q", value)
+		}
+		return nil
+	})
+	return c
+}
+
+func (c *cmd) StderrMatches(pattern string) Cmd {
+	c.stderrChecks = append(c.stderrChecks, func(v vars.Vars, stderr string) error {
+		pattern := vars.Expand(v, pattern)
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return err
+		}
+		if !re.MatchString(stderr) {
+			return fmt.Errorf("stderr does not match %q", pattern)
+		}
+		return nil
+	})
+	return c
+}
+
+func (c *cmd) Stdin(template string) Cmd {
+	c.stdin = func(v vars.Vars) (string, error) {
+		return vars.Expand(v, template), nil
+	}
+	return c
+}
+
+func (c *cmd) StdinFile(path string) Cmd {
+	c.stdin = func(v vars.Vars) (string, error) {
+		buf, err := ioutil.ReadFile(vars.Expand(v, path))
+		if err != nil {
+			return "", err
+		}
+		return vars.Expand(v, string(buf)), nil
+	}
+	return c
+}
+
+func (c *cmd) StdinFrom(key string) Cmd {
+	c.stdin = func(v vars.Vars) (string, error) {
+		if !v.Has(key) {
+			return "", fmt.Errorf("variable %v not set", key)
+		}
+		return vars.Expand(v, v.Get(key)), nil
+	}
+	return c
+}
+
+func (c *cmd) KillGrace(grace time.Duration) Cmd {
+	c.grace = grace
+	return c
+}
+
+// MaxDuration fails the command if it runs longer than d.
+func (c *cmd) MaxDuration(d time.Duration) Cmd {
+	c.maxDuration = d
+	return c
+}
+
+// MaxRSS fails the command if its maximum resident set size
+// exceeds n bytes.
+func (c *cmd) MaxRSS(n int64) Cmd {
+	c.maxRSS = n
 	return c
 }
 
 func (c *cmd) Eval(e gestalt.Evaluator) error {
+	return c.eval(e, nil)
+}
+
+// evaluate the command, reporting its progress to watch if not nil.
+func (c *cmd) eval(e gestalt.Evaluator, watch *cmdWatch) error {
+	if watch != nil {
+		defer watch.exited()
+	}
 
-	path := vars.Expand(e.Vars(), c.path)
-	args := vars.ExpandAll(e.Vars(), c.args)
+	inv := &cassette.Invocation{
+		Path: vars.Expand(e.Vars(), c.path),
+		Args: vars.ExpandAll(e.Vars(), c.args),
+		Dir:  vars.Expand(e.Vars(), c.dir),
+		Env:  c.envOverrides(e),
+	}
+	path, args := inv.Path, inv.Args
 
-	cmd := exec.CommandContext(e.Context(), path, args...)
+	if c.stdin != nil {
+		stdin, err := c.stdin(e.Vars())
+		if err != nil {
+			return fmt.Errorf("stdin: %v", err)
+		}
+		inv.Stdin = stdin
+	}
 
-	cmd.Dir = vars.Expand(e.Vars(), c.dir)
-	cmd.Env = vars.ExpandAll(e.Vars(), c.env)
+	var result *cmdResult
+	var err error
 
-	stdoutPipe, err := cmd.StdoutPipe()
+	if cas := cassette.FromContext(e.Context()); cas != nil && cas.Replaying() {
+		result, err = c.replay(e, cas, inv, watch)
+	} else {
+		result, err = c.run(e, inv, watch)
+		if err == nil && cas != nil {
+			cas.Record(inv)
+		}
+	}
 	if err != nil {
 		return err
 	}
 
+	if rec, ok := e.(gestalt.UsageRecorder); ok && result.usage != nil {
+		rec.RecordUsage(*result.usage)
+	}
+
+	if !expectedExecError(result) {
+		if err := c.checkExit(result.err, result.code); err != nil {
+			return newError(err, path, args, result)
+		}
+		for _, check := range c.stderrChecks {
+			if err := check(e.Vars(), result.stderr.String()); err != nil {
+				return newError(err, path, args, result)
+			}
+		}
+		if err := c.checkUsage(inv, result); err != nil {
+			return newError(err, path, args, result)
+		}
+	}
+
+	if c.copyStdout() {
+		buf := bytes.NewBuffer(result.stdout.Bytes())
+		err := c.fn(bufio.NewReader(buf), e)
+		if err != nil {
+			return newError(err, path, args, result)
+		}
+		return nil
+	}
+	return nil
+}
+
+func (c *cmd) checkUsage(inv *cassette.Invocation, result *cmdResult) error {
+	if c.maxDuration > 0 && inv.Duration > c.maxDuration {
+		return fmt.Errorf("took %v, expected at most %v", inv.Duration, c.maxDuration)
+	}
+	if c.maxRSS > 0 && result.usage != nil && result.usage.MaxRSS > c.maxRSS {
+		return fmt.Errorf("max rss %v bytes, expected at most %v", result.usage.MaxRSS, c.maxRSS)
+	}
+	return nil
+}
+
+type cmdResult struct {
+	stdout *bytes.Buffer
+	stderr *bytes.Buffer
+	code   int
+
+	// nil if the process wasn't run.
+	usage *gestalt.Usage
+
+	// from exec.Cmd.Wait()
+	err error
+
+	// terminated due to the context being cancelled.
+	killed bool
+}
+
+// run the process for inv, filling in its results.
+func (c *cmd) run(e gestalt.Evaluator, inv *cassette.Invocation, watch *cmdWatch) (*cmdResult, error) {
+	cmd := exec.Command(inv.Path, inv.Args...)
+	setProcessGroup(cmd)
+
+	cmd.Dir = inv.Dir
+	cmd.Env = append(c.baseEnv(), inv.Env...)
+
+	if c.stdin != nil {
+		cmd.Stdin = strings.NewReader(inv.Stdin)
+	}
+
+	stdoutPipe, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
 	stderrPipe, err := cmd.StderrPipe()
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	e.Message("running %v %v", inv.Path, strings.Join(inv.Args, " "))
+
+	// don't start commands once the evaluation has been cancelled.
+	if err := e.Context().Err(); err != nil {
+		return nil, fmt.Errorf("can't execute %v: %v", inv.Path, err)
 	}
 
-	e.Message("running %v %v", path, strings.Join(args, " "))
+	inv.Start = time.Now()
 
 	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("can't execute %v: %v", path, err)
+		return nil, fmt.Errorf("can't execute %v: %v", inv.Path, err)
+	}
+
+	stopKiller := killOnCancel(e.Context(), cmd, c.grace)
+
+	result := &cmdResult{
+		stdout: new(bytes.Buffer),
+		stderr: new(bytes.Buffer),
 	}
 
-	stdoutBuf := new(bytes.Buffer)
-	stderrBuf := new(bytes.Buffer)
+	stdoutLog, stderrLog := c.streamLogs(e)
+
+	if watch != nil {
+		watch.started(cmd.Process.Pid)
+		stdoutLog = teeLog(stdoutLog, watch.line)
+		stderrLog = teeLog(stderrLog, watch.line)
+	}
 
 	var wg sync.WaitGroup
 	wg.Add(2)
 	go func() {
 		defer wg.Done()
-		logStream(stdoutPipe, e.Logger().Dump, stdoutBuf)
+		logStream(stdoutPipe, stdoutLog, result.stdout)
 	}()
 	go func() {
 		defer wg.Done()
-		logStream(stderrPipe, e.Logger().Dump, stderrBuf)
+		logStream(stderrPipe, stderrLog, result.stderr)
 	}()
 	wg.Wait()
 
-	if err := cmd.Wait(); err != nil {
-		if !expectedExecError(err, e) {
-			return newError(err, path, args, stdoutBuf, stderrBuf)
-		}
+	result.err = cmd.Wait()
+	result.killed = stopKiller()
+
+	result.code = cmd.ProcessState.ExitCode()
+	result.usage = processUsage(cmd.ProcessState)
+
+	inv.Duration = time.Since(inv.Start)
+	inv.Stdout = result.stdout.String()
+	inv.Stderr = result.stderr.String()
+	inv.ExitCode = result.code
+	inv.Killed = result.killed
+
+	return result, nil
+}
+
+// serve the results of inv from the cassette, reporting them to watch
+// if not nil.  Commands which were terminated by cancellation while
+// recording stay running until the context is done.
+func (c *cmd) replay(e gestalt.Evaluator, cas *cassette.Cassette, inv *cassette.Invocation, watch *cmdWatch) (*cmdResult, error) {
+	e.Message("replaying %v %v", inv.Path, strings.Join(inv.Args, " "))
+
+	recorded, err := cas.Match(inv)
+	if err != nil {
+		return nil, err
 	}
 
-	if c.copyStdout() {
-		buf := bytes.NewBuffer(stdoutBuf.Bytes())
-		err := c.fn(bufio.NewReader(buf), e)
-		if err != nil {
-			return newError(err, path, args, stdoutBuf, stderrBuf)
+	inv.Duration = recorded.Duration
+
+	result := &cmdResult{
+		stdout: new(bytes.Buffer),
+		stderr: new(bytes.Buffer),
+		code:   recorded.ExitCode,
+	}
+
+	stdoutLog, stderrLog := c.streamLogs(e)
+
+	if watch != nil {
+		// there's no process to report.
+		watch.started(0)
+		stdoutLog = teeLog(stdoutLog, watch.line)
+		stderrLog = teeLog(stderrLog, watch.line)
+	}
+
+	logStream(ioutil.NopCloser(strings.NewReader(recorded.Stdout)), stdoutLog, result.stdout)
+	logStream(ioutil.NopCloser(strings.NewReader(recorded.Stderr)), stderrLog, result.stderr)
+
+	if recorded.Killed {
+		<-e.Context().Done()
+		result.err = e.Context().Err()
+		result.killed = true
+	}
+
+	return result, nil
+}
+
+func (c *cmd) streamLogs(e gestalt.Evaluator) (func(string), func(string)) {
+	if !c.streamOutput(e) {
+		return nil, nil
+	}
+	return e.Logger().CloneFor(e.Path() + " [stdout]").Dump,
+		e.Logger().CloneFor(e.Path() + " [stderr]").Dump
+}
+
+// verify the exit status against the expected codes.
+func (c *cmd) checkExit(err error, code int) error {
+	if _, ok := err.(*exec.ExitError); err != nil && !ok {
+		return err
+	}
+
+	switch {
+	case len(c.exitCodes) > 0:
+		for _, expected := range c.exitCodes {
+			if code == expected {
+				return nil
+			}
+		}
+		return fmt.Errorf("exit status %v, expected %v", code, c.exitCodes)
+	case c.expectFailure:
+		if code == 0 {
+			return fmt.Errorf("exit status 0, expected failure")
 		}
 		return nil
 	}
-	return nil
+
+	// replayed results have no error from the process.
+	if err == nil && code != 0 {
+		return fmt.Errorf("exit status %v", code)
+	}
+	return err
+}
+
+// base environment for the mode.
+func (c *cmd) baseEnv() []string {
+	env := []string{}
+
+	switch c.envMode {
+	case envInherit:
+		env = append(env, os.Environ()...)
+	case envAllowlist:
+		for _, entry := range os.Environ() {
+			key := strings.SplitN(entry, "=", 2)[0]
+			for _, allowed := range c.envAllow {
+				if key == allowed {
+					env = append(env, entry)
+				}
+			}
+		}
+	}
+
+	return env
+}
+
+// variables set on top of the base environment: defaults from
+// enclosing components, then the command's own variables.
+// os/exec keeps the last value of duplicate keys.
+func (c *cmd) envOverrides(e gestalt.Evaluator) []string {
+	env := vars.ExpandAll(e.Vars(), gestalt.ContextEnv(e.Context()))
+
+	for _, kv := range c.env {
+		key := vars.Expand(e.Vars(), kv[0])
+		if !c.rawEnv {
+			key = strings.ToUpper(key)
+		}
+		env = append(env, key+"="+vars.Expand(e.Vars(), kv[1]))
+	}
+
+	return env
+}
+
+func (c *cmd) environ(e gestalt.Evaluator) []string {
+	return append(c.baseEnv(), c.envOverrides(e)...)
 }
 
 func (c *cmd) copyStdout() bool {
 	return c.fn != nil
 }
 
+func (c *cmd) streamOutput(e gestalt.Evaluator) bool {
+	if c.stream != nil {
+		return *c.stream
+	}
+	if s, ok := e.Logger().(gestalt.OutputStreamer); ok {
+		return s.StreamOutput()
+	}
+	return false
+}
+
+func teeLog(fns ...func(string)) func(string) {
+	return func(line string) {
+		for _, fn := range fns {
+			if fn != nil {
+				fn(line)
+			}
+		}
+	}
+}
+
+// copy reader into b, passing each complete line to log (if set).
 func logStream(reader io.ReadCloser, log func(string), b *bytes.Buffer) {
-	buf := make([]byte, 80)
+	r := bufio.NewReader(reader)
 	for {
-		n, err := reader.Read(buf)
+		line, err := r.ReadBytes('\n')
 
-		if b != nil && n > 0 {
-			b.Write(buf[0:n])
+		if b != nil && len(line) > 0 {
+			b.Write(line)
 		}
 
-		if err != nil {
-			break
+		if log != nil && len(line) > 0 {
+			log(string(line))
 		}
 
-		if n > 0 {
-			//log(string(buf[0:n]))
+		if err != nil {
+			break
 		}
 	}
 }
 
-// Fail silently if killed due to context being cancelled.
-func expectedExecError(err error, e gestalt.Evaluator) bool {
-	if exiterr, ok := err.(*exec.ExitError); ok && e.Context().Err() != nil {
-		if wstatus, ok := exiterr.Sys().(syscall.WaitStatus); ok {
-			if wstatus.Signal() == syscall.SIGKILL {
-				return true
-			}
-		}
-	}
-	return false
+// Fail silently if terminated due to context being cancelled,
+// however the command exits.
+func expectedExecError(result *cmdResult) bool {
+	return result.err != nil && result.killed
 }
 
-func newError(err error, path string, args []string, stdout *bytes.Buffer, stderr *bytes.Buffer) error {
-	return &Error{err.Error(), path, args, stdout.String(), stderr.String()}
+func newError(err error, path string, args []string, result *cmdResult) error {
+	return &Error{
+		message: err.Error(),
+		path:    path,
+		args:    args,
+		code:    result.code,
+		usage:   result.usage,
+		stdout:  result.stdout.String(),
+		stderr:  result.stderr.String(),
+	}
 }
@@ -4,12 +4,16 @@ import (
 	"bytes"
 	"fmt"
 	"strings"
+
+	"github.com/ovrclk/gestalt"
 )
 
 type Error struct {
 	message string
 	path    string
 	args    []string
+	code    int
+	usage   *gestalt.Usage
 
 	stdout string
 	stderr string
@@ -19,6 +23,18 @@ func (e *Error) Error() string {
 	return fmt.Sprintf("%v %v: %v", e.path, strings.Join(e.args, " "), e.message)
 }
 
+// ExitCode returns the exit code of the process, or -1 if it
+// was terminated by a signal.
+func (e *Error) ExitCode() int {
+	return e.code
+}
+
+// Usage returns the resources used by the process, or nil if it
+// wasn't run.
+func (e *Error) Usage() *gestalt.Usage {
+	return e.usage
+}
+
 func (e *Error) Stdout() string {
 	return e.stdout
 }
@@ -35,5 +51,8 @@ func (e *Error) Detail() string {
 	buf.WriteString("\n-===[BEGIN STDERR]===-\n")
 	buf.WriteString(e.stderr)
 	buf.WriteString("\n-===[END STDERR]===-\n")
+	if e.usage != nil {
+		fmt.Fprintf(buf, "\nexit code %v, %v\n", e.code, e.usage)
+	}
 	return buf.String()
 }
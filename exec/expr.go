@@ -0,0 +1,615 @@
+package exec
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/ovrclk/gestalt"
+	"github.com/ovrclk/gestalt/vars"
+)
+
+// GrepExpr keeps objects for which the expression is true.  See
+// EnsureAllExpr for the expression syntax.
+func (p *pipeline) GrepExpr(src string) Pipeline {
+	return p.Then(func(objs []PipeObject, e gestalt.Evaluator) ([]PipeObject, error) {
+		expanded, expr, err := compileExpr(e, src)
+		if err != nil {
+			return objs, err
+		}
+		result := make([]PipeObject, 0)
+		for _, obj := range objs {
+			ok, err := evalBool(expr, obj.lookup)
+			if err != nil {
+				return objs, pipeError([]PipeObject{obj}, "expression %q: %v", expanded, err)
+			}
+			if ok {
+				result = append(result, obj)
+			}
+		}
+		return result, nil
+	})
+}
+
+// EnsureExpr fails unless the expression is true.  The number of
+// objects is available as count: EnsureExpr("count > 0").
+func (p *pipeline) EnsureExpr(src string) Pipeline {
+	return p.Then(func(objs []PipeObject, e gestalt.Evaluator) ([]PipeObject, error) {
+		expanded, expr, err := compileExpr(e, src)
+		if err != nil {
+			return objs, err
+		}
+		env := func(name string) (string, bool) {
+			if name == "count" {
+				return strconv.Itoa(len(objs)), true
+			}
+			return "", false
+		}
+		ok, err := evalBool(expr, env)
+		switch {
+		case err != nil:
+			return objs, pipeError(objs, "expression %q: %v", expanded, err)
+		case !ok:
+			return objs, pipeError(objs, "expression %q is false", expanded)
+		}
+		return objs, nil
+	})
+}
+
+// EnsureAllExpr fails unless the expression is true for every object.
+//
+// Expressions are evaluated with the fields of an object as variables;
+// fields whose names aren't identifiers are read with field("NAME").
+// They support string ('a' or "a"), number and boolean literals, the
+// operators || && == != < <= > >= + - * / % ! and parentheses, and the
+// functions int, float, string, len, contains(s, sub) and
+// matches(s, regex).  Strings are compared as numbers when compared
+// with a number.  {{vars}} are expanded before parsing.
+func (p *pipeline) EnsureAllExpr(src string) Pipeline {
+	return p.Then(func(objs []PipeObject, e gestalt.Evaluator) ([]PipeObject, error) {
+		expanded, expr, err := compileExpr(e, src)
+		if err != nil {
+			return objs, err
+		}
+		failed := make([]PipeObject, 0)
+		for _, obj := range objs {
+			ok, err := evalBool(expr, obj.lookup)
+			if err != nil {
+				return objs, pipeError([]PipeObject{obj}, "expression %q: %v", expanded, err)
+			}
+			if !ok {
+				failed = append(failed, obj)
+			}
+		}
+		if len(failed) > 0 {
+			return objs, pipeError(failed, "%v of %v rows failed expression %q",
+				len(failed), len(objs), expanded)
+		}
+		return objs, nil
+	})
+}
+
+func (obj PipeObject) lookup(name string) (string, bool) {
+	v, ok := obj[name]
+	return v, ok
+}
+
+func compileExpr(e gestalt.Evaluator, src string) (string, exprNode, error) {
+	expanded := vars.Expand(e.Vars(), src)
+	expr, err := parseExpr(expanded)
+	if err != nil {
+		return expanded, nil, fmt.Errorf("expression %q: %v", expanded, err)
+	}
+	return expanded, expr, nil
+}
+
+func evalBool(expr exprNode, env exprEnv) (bool, error) {
+	v, err := expr.eval(env)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("result %v is not a boolean", fmtValue(v))
+	}
+	return b, nil
+}
+
+/* evaluation */
+
+// values are strings, float64s or bools.
+type exprEnv func(string) (string, bool)
+
+type exprNode interface {
+	eval(exprEnv) (interface{}, error)
+}
+
+type exprLiteral struct {
+	value interface{}
+}
+
+type exprIdent struct {
+	name string
+}
+
+type exprUnary struct {
+	op string
+	x  exprNode
+}
+
+type exprBinary struct {
+	op   string
+	x, y exprNode
+}
+
+type exprCall struct {
+	fn   string
+	args []exprNode
+}
+
+func (n *exprLiteral) eval(_ exprEnv) (interface{}, error) {
+	return n.value, nil
+}
+
+func (n *exprIdent) eval(env exprEnv) (interface{}, error) {
+	if v, ok := env(n.name); ok {
+		return v, nil
+	}
+	return nil, fmt.Errorf("undefined: %v", n.name)
+}
+
+func (n *exprUnary) eval(env exprEnv) (interface{}, error) {
+	x, err := n.x.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "!":
+		b, ok := x.(bool)
+		if !ok {
+			return nil, fmt.Errorf("!%v: not a boolean", fmtValue(x))
+		}
+		return !b, nil
+	default:
+		f, err := toNumber(x)
+		if err != nil {
+			return nil, err
+		}
+		return -f, nil
+	}
+}
+
+func (n *exprBinary) eval(env exprEnv) (interface{}, error) {
+	x, err := n.x.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	// short-circuit
+	if n.op == "&&" || n.op == "||" {
+		bx, ok := x.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%v %v: not a boolean", fmtValue(x), n.op)
+		}
+		if (n.op == "&&" && !bx) || (n.op == "||" && bx) {
+			return bx, nil
+		}
+		y, err := n.y.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		by, ok := y.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%v %v: not a boolean", n.op, fmtValue(y))
+		}
+		return by, nil
+	}
+
+	y, err := n.y.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==", "!=", "<", "<=", ">", ">=":
+		c, err := compareExprValues(x, y, n.op)
+		if err != nil {
+			return nil, err
+		}
+		switch n.op {
+		case "==":
+			return c == 0, nil
+		case "!=":
+			return c != 0, nil
+		case "<":
+			return c < 0, nil
+		case "<=":
+			return c <= 0, nil
+		case ">":
+			return c > 0, nil
+		default:
+			return c >= 0, nil
+		}
+	}
+
+	fx, err := toNumber(x)
+	if err != nil {
+		return nil, err
+	}
+	fy, err := toNumber(y)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "+":
+		return fx + fy, nil
+	case "-":
+		return fx - fy, nil
+	case "*":
+		return fx * fy, nil
+	case "/":
+		if fy == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return fx / fy, nil
+	default:
+		if int64(fy) == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return float64(int64(fx) % int64(fy)), nil
+	}
+}
+
+func (n *exprCall) eval(env exprEnv) (interface{}, error) {
+	args := make([]interface{}, 0, len(n.args))
+	for _, arg := range n.args {
+		v, err := arg.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, v)
+	}
+
+	nargs := map[string]int{
+		"int": 1, "float": 1, "string": 1, "len": 1, "field": 1,
+		"contains": 2, "matches": 2,
+	}
+	if expected, ok := nargs[n.fn]; !ok {
+		return nil, fmt.Errorf("unknown function %v", n.fn)
+	} else if len(args) != expected {
+		return nil, fmt.Errorf("%v: expected %v arguments, have %v", n.fn, expected, len(args))
+	}
+
+	switch n.fn {
+	case "int":
+		f, err := toNumber(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return float64(int64(f)), nil
+	case "float":
+		return toNumber(args[0])
+	case "string":
+		return toString(args[0]), nil
+	case "len":
+		return float64(len(toString(args[0]))), nil
+	case "field":
+		return (&exprIdent{toString(args[0])}).eval(env)
+	case "contains":
+		return strings.Contains(toString(args[0]), toString(args[1])), nil
+	default:
+		re, err := regexp.Compile(toString(args[1]))
+		if err != nil {
+			return nil, err
+		}
+		return re.MatchString(toString(args[0])), nil
+	}
+}
+
+// compare strings as strings, and anything else as numbers.
+func compareExprValues(x, y interface{}, op string) (int, error) {
+	if bx, ok := x.(bool); ok {
+		by, ok := y.(bool)
+		if !ok || (op != "==" && op != "!=") {
+			return 0, fmt.Errorf("can't compare %v %v %v", fmtValue(x), op, fmtValue(y))
+		}
+		if bx == by {
+			return 0, nil
+		}
+		return 1, nil
+	}
+
+	sx, xstr := x.(string)
+	sy, ystr := y.(string)
+	if xstr && ystr {
+		return strings.Compare(sx, sy), nil
+	}
+
+	fx, err := toNumber(x)
+	if err != nil {
+		return 0, err
+	}
+	fy, err := toNumber(y)
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case fx < fy:
+		return -1, nil
+	case fx > fy:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+func toNumber(v interface{}) (float64, error) {
+	switch v := v.(type) {
+	case float64:
+		return v, nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return 0, fmt.Errorf("%q is not a number", v)
+		}
+		return f, nil
+	}
+	return 0, fmt.Errorf("%v is not a number", fmtValue(v))
+}
+
+func toString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
+
+func fmtValue(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return strconv.Quote(s)
+	}
+	return toString(v)
+}
+
+/* parsing */
+
+type exprToken struct {
+	kind  rune // 'n'umber, 's'tring, 'i'dentifier, 'o'perator, 0 at end
+	value string
+}
+
+func tokenizeExpr(src string) ([]exprToken, error) {
+	var tokens []exprToken
+	runes := []rune(src)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case unicode.IsDigit(r):
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, exprToken{'n', string(runes[start:i])})
+
+		case r == '\'' || r == '"':
+			buf := new(strings.Builder)
+			i++
+			for ; i < len(runes) && runes[i] != r; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				buf.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			i++
+			tokens = append(tokens, exprToken{'s', buf.String()})
+
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) ||
+				runes[i] == '_' || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, exprToken{'i', string(runes[start:i])})
+
+		default:
+			op := ""
+			if i+1 < len(runes) {
+				switch two := string(runes[i : i+2]); two {
+				case "&&", "||", "==", "!=", "<=", ">=":
+					op = two
+				}
+			}
+			if op == "" && strings.ContainsRune("<>!+-*/%(),", r) {
+				op = string(r)
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected %q", r)
+			}
+			i += len([]rune(op))
+			tokens = append(tokens, exprToken{'o', op})
+		}
+	}
+
+	return tokens, nil
+}
+
+type exprParser struct {
+	tokens []exprToken
+	pos    int
+}
+
+func parseExpr(src string) (exprNode, error) {
+	tokens, err := tokenizeExpr(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{tokens: tokens}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != 0 {
+		return nil, fmt.Errorf("unexpected %q", tok.value)
+	}
+	return node, nil
+}
+
+func (p *exprParser) peek() exprToken {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return exprToken{}
+}
+
+func (p *exprParser) next() exprToken {
+	tok := p.peek()
+	p.pos++
+	return tok
+}
+
+// consume the next token if it is one of the given operators.
+func (p *exprParser) accept(ops ...string) (string, bool) {
+	tok := p.peek()
+	if tok.kind != 'o' {
+		return "", false
+	}
+	for _, op := range ops {
+		if tok.value == op {
+			p.pos++
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *exprParser) parseBinary(next func() (exprNode, error), ops ...string) (exprNode, error) {
+	x, err := next()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept(ops...)
+		if !ok {
+			return x, nil
+		}
+		y, err := next()
+		if err != nil {
+			return nil, err
+		}
+		x = &exprBinary{op, x, y}
+	}
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	return p.parseBinary(p.parseAnd, "||")
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	return p.parseBinary(p.parseCompare, "&&")
+}
+
+func (p *exprParser) parseCompare() (exprNode, error) {
+	x, err := p.parseAdd()
+	if err != nil {
+		return nil, err
+	}
+	if op, ok := p.accept("==", "!=", "<", "<=", ">", ">="); ok {
+		y, err := p.parseAdd()
+		if err != nil {
+			return nil, err
+		}
+		return &exprBinary{op, x, y}, nil
+	}
+	return x, nil
+}
+
+func (p *exprParser) parseAdd() (exprNode, error) {
+	return p.parseBinary(p.parseMul, "+", "-")
+}
+
+func (p *exprParser) parseMul() (exprNode, error) {
+	return p.parseBinary(p.parseUnary, "*", "/", "%")
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if op, ok := p.accept("!", "-"); ok {
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &exprUnary{op, x}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	tok := p.next()
+
+	switch tok.kind {
+	case 'n':
+		f, err := strconv.ParseFloat(tok.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %v", tok.value)
+		}
+		return &exprLiteral{f}, nil
+
+	case 's':
+		return &exprLiteral{tok.value}, nil
+
+	case 'i':
+		switch tok.value {
+		case "true":
+			return &exprLiteral{true}, nil
+		case "false":
+			return &exprLiteral{false}, nil
+		}
+		if _, ok := p.accept("("); !ok {
+			return &exprIdent{tok.value}, nil
+		}
+		call := &exprCall{fn: tok.value}
+		if _, ok := p.accept(")"); ok {
+			return call, nil
+		}
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			call.args = append(call.args, arg)
+			if _, ok := p.accept(")"); ok {
+				return call, nil
+			}
+			if _, ok := p.accept(","); !ok {
+				return nil, fmt.Errorf("expected , or ) in call to %v", tok.value)
+			}
+		}
+
+	case 'o':
+		if tok.value == "(" {
+			x, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := p.accept(")"); !ok {
+				return nil, fmt.Errorf("missing )")
+			}
+			return x, nil
+		}
+		return nil, fmt.Errorf("unexpected %q", tok.value)
+	}
+
+	return nil, fmt.Errorf("unexpected end of expression")
+}
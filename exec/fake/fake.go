@@ -0,0 +1,273 @@
+package fake
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Fake is a directory of stub executables which is prepended to PATH
+// until Close is called.  Stubs record their invocations and answer
+// with scripted output and exit codes.
+type Fake struct {
+	dir      string
+	oldPath  string
+	commands map[string]*Command
+	err      error
+	mtx      sync.Mutex
+}
+
+func New() (*Fake, error) {
+	dir, err := ioutil.TempDir("", "gestalt-fake")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.Mkdir(filepath.Join(dir, ".calls"), 0755); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	f := &Fake{
+		dir:      dir,
+		oldPath:  os.Getenv("PATH"),
+		commands: make(map[string]*Command),
+	}
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+f.oldPath)
+	return f, nil
+}
+
+// Dir returns the directory containing the stubs.
+func (f *Fake) Dir() string {
+	return f.dir
+}
+
+// Close restores PATH and removes the stubs.  It returns the first
+// error encountered while writing stubs, if any.
+func (f *Fake) Close() error {
+	os.Setenv("PATH", f.oldPath)
+	if err := os.RemoveAll(f.dir); err != nil && f.err == nil {
+		return err
+	}
+	return f.err
+}
+
+// Command returns the stub for name, creating it if necessary.  Until
+// configured otherwise, it exits successfully without output.
+func (f *Fake) Command(name string) *Command {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	if c, ok := f.commands[name]; ok {
+		return c
+	}
+
+	c := &Command{fake: f, name: name}
+	c.otherwise = &Response{cmd: c}
+	f.commands[name] = c
+
+	f.setErr(c.write())
+	return c
+}
+
+func (f *Fake) setErr(err error) {
+	if err != nil && f.err == nil {
+		f.err = err
+	}
+}
+
+type Command struct {
+	fake      *Fake
+	name      string
+	responses []*Response
+	otherwise *Response
+}
+
+type Call struct {
+	Args []string
+}
+
+type Response struct {
+	cmd *Command
+
+	args    []string
+	pattern string
+
+	stdout string
+	stderr string
+	code   int
+}
+
+// On adds a response for invocations with exactly the given args.
+func (c *Command) On(args ...string) *Response {
+	return c.add(&Response{cmd: c, args: args})
+}
+
+// OnMatch adds a response for invocations whose space-separated args
+// match the extended regular expression pattern.
+func (c *Command) OnMatch(pattern string) *Response {
+	return c.add(&Response{cmd: c, pattern: pattern})
+}
+
+// Otherwise returns the response for invocations not matched by any
+// other response.
+func (c *Command) Otherwise() *Response {
+	return c.otherwise
+}
+
+func (c *Command) add(r *Response) *Response {
+	c.responses = append(c.responses, r)
+	c.update()
+	return r
+}
+
+// Calls returns the recorded invocations, oldest first.
+func (c *Command) Calls() []Call {
+	pattern := filepath.Join(c.fake.dir, ".calls", c.name+".*")
+	paths, _ := filepath.Glob(pattern)
+
+	type entry struct {
+		path string
+		seq  int
+	}
+
+	// records are named after the command and their sequence number.
+	var entries []entry
+	for _, path := range paths {
+		seq, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(path), c.name+"."))
+		if err == nil {
+			entries = append(entries, entry{path, seq})
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].seq < entries[j].seq
+	})
+
+	var calls []Call
+	for _, entry := range entries {
+		buf, err := ioutil.ReadFile(entry.path)
+		if err != nil {
+			continue
+		}
+		args := []string{}
+		for _, arg := range bytes.Split(buf, []byte{0}) {
+			args = append(args, string(arg))
+		}
+		// drop the element after the trailing separator.
+		calls = append(calls, Call{args[0 : len(args)-1]})
+	}
+	return calls
+}
+
+// CalledWith returns the number of invocations with exactly args.
+func (c *Command) CalledWith(args ...string) int {
+	count := 0
+	for _, call := range c.Calls() {
+		if equal(call.Args, args) {
+			count++
+		}
+	}
+	return count
+}
+
+func (r *Response) Stdout(value string) *Response {
+	r.stdout = value
+	r.cmd.update()
+	return r
+}
+
+func (r *Response) Stderr(value string) *Response {
+	r.stderr = value
+	r.cmd.update()
+	return r
+}
+
+func (r *Response) Exit(code int) *Response {
+	r.code = code
+	r.cmd.update()
+	return r
+}
+
+func (c *Command) update() {
+	c.fake.mtx.Lock()
+	defer c.fake.mtx.Unlock()
+	c.fake.setErr(c.write())
+}
+
+// (re)generate the stub script.  Output is kept in files next to the
+// script to avoid quoting it.
+func (c *Command) write() error {
+	script := new(bytes.Buffer)
+	base := filepath.Join(c.fake.dir, c.name)
+	calls := filepath.Join(c.fake.dir, ".calls")
+
+	// number the call from a counter shared by all stubs, holding a
+	// lock directory while updating it.
+	fmt.Fprintf(script, "#!/bin/sh\n")
+	fmt.Fprintf(script, "calls=%v\n", quote(calls))
+	fmt.Fprintf(script, "until mkdir \"$calls/.lock\" 2>/dev/null; do sleep 0.01 || exit 125; done\n")
+	fmt.Fprintf(script, "seq=$(($(cat \"$calls/.seq\" 2>/dev/null || echo 0) + 1))\n")
+	fmt.Fprintf(script, "echo $seq > \"$calls/.seq\"\n")
+	fmt.Fprintf(script, "rmdir \"$calls/.lock\"\n")
+	fmt.Fprintf(script, "call=\"$calls\"/%v.$seq\n", quote(c.name))
+	fmt.Fprintf(script, "for arg in \"$@\"; do printf '%%s\\0' \"$arg\"; done > \"$call\"\n")
+
+	respond := func(idx string, r *Response) error {
+		for stream, value := range map[string]string{"stdout": r.stdout, "stderr": r.stderr} {
+			path := fmt.Sprintf("%v.%v.%v", base, idx, stream)
+			if err := ioutil.WriteFile(path, []byte(value), 0644); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintf(script, "  cat %v.%v.stdout\n", quote(base), idx)
+		fmt.Fprintf(script, "  cat %v.%v.stderr >&2\n", quote(base), idx)
+		fmt.Fprintf(script, "  exit %v\n", r.code)
+		return nil
+	}
+
+	for i, r := range c.responses {
+		if r.pattern != "" {
+			fmt.Fprintf(script, "if printf '%%s' \"$*\" | grep -Eq %v; then\n", quote(r.pattern))
+		} else {
+			conds := []string{fmt.Sprintf("[ $# -eq %v ]", len(r.args))}
+			for j, arg := range r.args {
+				conds = append(conds, fmt.Sprintf("[ \"${%v}\" = %v ]", j+1, quote(arg)))
+			}
+			fmt.Fprintf(script, "if %v; then\n", strings.Join(conds, " && "))
+		}
+		if err := respond(fmt.Sprint(i), r); err != nil {
+			return err
+		}
+		fmt.Fprintf(script, "fi\n")
+	}
+
+	if err := respond("otherwise", c.otherwise); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(base, script.Bytes(), 0755)
+}
+
+// quote s for the shell.
+func quote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,72 @@
+package fake_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/ovrclk/gestalt"
+	g "github.com/ovrclk/gestalt/builder"
+	"github.com/ovrclk/gestalt/exec/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFake(t *testing.T) {
+	f, err := fake.New()
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, f.Close()) }()
+
+	cli := f.Command("akash")
+	cli.On("user:create", "u1").Stdout("created u1 id-1\n")
+	cli.OnMatch(`^user:delete `).Stderr("not found\n").Exit(3)
+	cli.Otherwise().Exit(1)
+
+	suite := g.Suite("users").
+		Run(g.EXEC("create", "akash", "user:create", "{{user-name}}").
+			FN(g.Capture("_", "_", "user-id")).
+			WithMeta(g.Export("user-id"))).
+		Run(g.EXEC("delete", "akash", "user:delete", "{{user-id}}").
+			ExpectExit(3).
+			StderrContains("not found"))
+
+	run(t, suite, []string{"-suser-name=u1"}, 0)
+
+	assert.Equal(t, 1, cli.CalledWith("user:create", "u1"))
+	assert.Equal(t, 1, cli.CalledWith("user:delete", "id-1"))
+	assert.Len(t, cli.Calls(), 2)
+
+	run(t, g.EXEC("unknown", "akash", "it's", "quoted"), nil, 1)
+	assert.Equal(t, 1, cli.CalledWith("it's", "quoted"))
+}
+
+func TestFake_order(t *testing.T) {
+	f, err := fake.New()
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, f.Close()) }()
+
+	cli := f.Command("akash")
+	f.Command("akash.other")
+
+	suite := g.Suite("calls")
+	var expected []fake.Call
+	for i := 0; i < 20; i++ {
+		arg := strconv.Itoa(i)
+		suite.Run(g.EXEC("call-"+arg, "akash", arg))
+		expected = append(expected, fake.Call{Args: []string{arg}})
+	}
+	suite.Run(g.EXEC("other", "akash.other"))
+
+	run(t, suite, nil, 0)
+
+	assert.Equal(t, expected, cli.Calls())
+}
+
+func run(t *testing.T, c gestalt.Component, args []string, expected int) {
+	status := 0
+	gestalt.NewRunner().
+		WithComponent(c).
+		WithArgs(append([]string{"eval"}, args...)).
+		WithTerminate(func(s int) { status = s }).
+		Run()
+	assert.Equal(t, expected, status)
+}
@@ -0,0 +1,357 @@
+package exec
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/creack/pty"
+	"github.com/ovrclk/gestalt"
+	"github.com/ovrclk/gestalt/cassette"
+	"github.com/ovrclk/gestalt/vars"
+)
+
+// DefaultStepTimeout is the time an Expect step waits for its pattern
+// unless overridden with StepTimeout or ExpectWithin.
+var DefaultStepTimeout = 10 * time.Second
+
+// Interactive runs a command attached to a pseudo-terminal and drives
+// it with a script of Expect and Send steps.
+type Interactive interface {
+	gestalt.Component
+	Dir(string) Interactive
+	AddEnv(string, string) Interactive
+
+	Expect(string) Interactive
+	ExpectWithin(string, time.Duration) Interactive
+	Send(string) Interactive
+	SendLine(string) Interactive
+	Emit(...string) Interactive
+	StepTimeout(time.Duration) Interactive
+}
+
+type interactiveStep struct {
+	expect  string
+	timeout time.Duration
+	emit    []string
+
+	send string
+}
+
+type interactive struct {
+	cmd     *cmd
+	steps   []*interactiveStep
+	timeout time.Duration
+}
+
+func NewInteractive(name string, path string, args []string) Interactive {
+	return &interactive{
+		cmd:     NewCmd(name, path, args).(*cmd),
+		timeout: DefaultStepTimeout,
+	}
+}
+
+func (c *interactive) Name() string {
+	return c.cmd.Name()
+}
+
+func (c *interactive) IsPassThrough() bool {
+	return false
+}
+
+func (c *interactive) WithMeta(m vars.Meta) gestalt.Component {
+	c.cmd.WithMeta(m)
+	return c
+}
+
+func (c *interactive) Meta() vars.Meta {
+	return c.cmd.Meta()
+}
+
+func (c *interactive) Dir(dir string) Interactive {
+	c.cmd.Dir(dir)
+	return c
+}
+
+func (c *interactive) AddEnv(k, v string) Interactive {
+	c.cmd.AddEnv(k, v)
+	return c
+}
+
+func (c *interactive) Expect(pattern string) Interactive {
+	return c.ExpectWithin(pattern, 0)
+}
+
+// ExpectWithin waits at most timeout for output matching pattern.
+func (c *interactive) ExpectWithin(pattern string, timeout time.Duration) Interactive {
+	c.steps = append(c.steps, &interactiveStep{expect: pattern, timeout: timeout})
+	return c
+}
+
+func (c *interactive) Send(template string) Interactive {
+	c.steps = append(c.steps, &interactiveStep{send: template})
+	return c
+}
+
+func (c *interactive) SendLine(template string) Interactive {
+	return c.Send(template + "\n")
+}
+
+// Emit stores the groups matched by the preceding Expect into the
+// given variables, in order.  Empty names skip a group.
+func (c *interactive) Emit(keys ...string) Interactive {
+	for i := len(c.steps) - 1; i >= 0; i-- {
+		if step := c.steps[i]; step.expect != "" {
+			step.emit = append(step.emit, keys...)
+			break
+		}
+	}
+	return c
+}
+
+func (c *interactive) StepTimeout(timeout time.Duration) Interactive {
+	c.timeout = timeout
+	return c
+}
+
+func (c *interactive) Eval(e gestalt.Evaluator) error {
+	inv := &cassette.Invocation{
+		Path: vars.Expand(e.Vars(), c.cmd.path),
+		Args: vars.ExpandAll(e.Vars(), c.cmd.args),
+		Dir:  vars.Expand(e.Vars(), c.cmd.dir),
+		Env:  c.cmd.envOverrides(e),
+	}
+	path, args := inv.Path, inv.Args
+
+	var result *interactiveResult
+	var err error
+
+	if cas := cassette.FromContext(e.Context()); cas != nil && cas.Replaying() {
+		result, err = c.replay(e, cas, inv)
+	} else {
+		result, err = c.run(e, inv)
+		if err == nil && cas != nil {
+			cas.Record(inv)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if rec, ok := e.(gestalt.UsageRecorder); ok && result.usage != nil {
+		rec.RecordUsage(*result.usage)
+	}
+
+	if result.scriptErr != nil {
+		return newError(result.scriptErr, path, args, result.cmdResult)
+	}
+
+	if result.err != nil && !expectedExecError(result.cmdResult) {
+		return newError(result.err, path, args, result.cmdResult)
+	}
+
+	return nil
+}
+
+type interactiveResult struct {
+	*cmdResult
+
+	// why the script didn't complete, if it didn't.
+	scriptErr error
+}
+
+// run the process for inv under the script, filling in its results.
+func (c *interactive) run(e gestalt.Evaluator, inv *cassette.Invocation) (*interactiveResult, error) {
+	cmd := exec.Command(inv.Path, inv.Args...)
+	cmd.Dir = inv.Dir
+	cmd.Env = append(c.cmd.baseEnv(), inv.Env...)
+
+	e.Message("running %v %v", inv.Path, strings.Join(inv.Args, " "))
+
+	if err := e.Context().Err(); err != nil {
+		return nil, fmt.Errorf("can't execute %v: %v", inv.Path, err)
+	}
+
+	inv.Start = time.Now()
+
+	// pty.Start places the process in a new session, and so in its
+	// own process group.
+	ptmx, err := pty.Start(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("can't execute %v: %v", inv.Path, err)
+	}
+	defer ptmx.Close()
+
+	stopKiller := killOnCancel(e.Context(), cmd, c.cmd.grace)
+
+	out := newPtyOutput()
+	go out.readFrom(ptmx)
+
+	scriptErr := c.runScript(e, ptmx, out)
+	if scriptErr != nil {
+		killProcessGroup(cmd)
+	}
+
+	err = cmd.Wait()
+	killed := stopKiller()
+
+	result := &cmdResult{
+		stdout: bytes.NewBufferString(out.String()),
+		stderr: new(bytes.Buffer),
+		code:   cmd.ProcessState.ExitCode(),
+		usage:  processUsage(cmd.ProcessState),
+		err:    err,
+		killed: killed,
+	}
+
+	inv.Duration = time.Since(inv.Start)
+	inv.Stdout = result.stdout.String()
+	inv.ExitCode = result.code
+	inv.Killed = result.killed
+
+	return &interactiveResult{result, scriptErr}, nil
+}
+
+// run the script against the terminal output recorded for inv.  What
+// the script sends is discarded.
+func (c *interactive) replay(e gestalt.Evaluator, cas *cassette.Cassette, inv *cassette.Invocation) (*interactiveResult, error) {
+	e.Message("replaying %v %v", inv.Path, strings.Join(inv.Args, " "))
+
+	recorded, err := cas.Match(inv)
+	if err != nil {
+		return nil, err
+	}
+
+	inv.Duration = recorded.Duration
+
+	out := newPtyOutput()
+	out.buf.WriteString(recorded.Stdout)
+	out.done = true
+
+	scriptErr := c.runScript(e, ioutil.Discard, out)
+
+	result := &cmdResult{
+		stdout: bytes.NewBufferString(recorded.Stdout),
+		stderr: new(bytes.Buffer),
+		code:   recorded.ExitCode,
+		killed: recorded.Killed,
+	}
+	if result.code != 0 {
+		result.err = fmt.Errorf("exit status %v", result.code)
+	}
+
+	return &interactiveResult{result, scriptErr}, nil
+}
+
+func (c *interactive) runScript(e gestalt.Evaluator, ptmx io.Writer, out *ptyOutput) error {
+	offset := 0
+
+	for _, step := range c.steps {
+		if step.expect == "" {
+			if _, err := io.WriteString(ptmx, vars.Expand(e.Vars(), step.send)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		pattern := vars.Expand(e.Vars(), step.expect)
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return err
+		}
+
+		timeout := step.timeout
+		if timeout <= 0 {
+			timeout = c.timeout
+		}
+		timer := time.NewTimer(timeout)
+
+		for {
+			data, done := out.since(offset)
+
+			if m := re.FindStringSubmatchIndex(data); m != nil {
+				for i, key := range step.emit {
+					if key == "" || 2*i+3 >= len(m) || m[2*i+2] < 0 {
+						continue
+					}
+					e.Vars().Put(key, data[m[2*i+2]:m[2*i+3]])
+				}
+				offset += m[1]
+				break
+			}
+
+			if done {
+				timer.Stop()
+				return fmt.Errorf("output closed while expecting %q", pattern)
+			}
+
+			select {
+			case <-out.updatech:
+			case <-timer.C:
+				return fmt.Errorf("timed out after %v expecting %q", timeout, pattern)
+			case <-e.Context().Done():
+				timer.Stop()
+				return e.Context().Err()
+			}
+		}
+
+		timer.Stop()
+	}
+
+	return nil
+}
+
+// output read from the terminal.  updatech is signalled whenever more
+// output is available or the terminal is closed.
+type ptyOutput struct {
+	mtx      sync.Mutex
+	buf      bytes.Buffer
+	done     bool
+	updatech chan struct{}
+}
+
+func newPtyOutput() *ptyOutput {
+	return &ptyOutput{updatech: make(chan struct{}, 1)}
+}
+
+func (o *ptyOutput) readFrom(f *os.File) {
+	buf := make([]byte, 1024)
+	for {
+		n, err := f.Read(buf)
+
+		o.mtx.Lock()
+		o.buf.Write(buf[0:n])
+		if err != nil {
+			o.done = true
+		}
+		o.mtx.Unlock()
+
+		select {
+		case o.updatech <- struct{}{}:
+		default:
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (o *ptyOutput) since(offset int) (string, bool) {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	return string(o.buf.Bytes()[offset:]), o.done
+}
+
+func (o *ptyOutput) String() string {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	return o.buf.String()
+}
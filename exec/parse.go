@@ -0,0 +1,189 @@
+package exec
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ovrclk/gestalt"
+	"github.com/ovrclk/gestalt/vars"
+)
+
+const maxJSONLine = 1024 * 1024
+
+var tableHeaderSep = regexp.MustCompile(`\s{2,}`)
+
+// ParseTable parses fixed-width tables, as printed by `kubectl get` or
+// `docker ps`.  The first line holds the column headers, which are used
+// as keys.  Headers are separated by two or more spaces, so they may
+// contain single spaces ("CONTAINER ID"); cells are cut at the header
+// positions, so they may contain spaces or be empty.
+func ParseTable() Pipeline {
+	return NewPipeline(func(r *bufio.Reader, e gestalt.Evaluator) ([]PipeObject, error) {
+		results := make([]PipeObject, 0)
+
+		scanner := bufio.NewScanner(r)
+
+		var columns []tableColumn
+
+		for scanner.Scan() {
+			line := []rune(strings.TrimRight(scanner.Text(), " \t\r"))
+
+			if len(line) == 0 {
+				continue
+			}
+
+			if columns == nil {
+				columns = tableColumns(string(line))
+				continue
+			}
+
+			obj := make(PipeObject)
+			for i, col := range columns {
+				end := len(line)
+				if i+1 < len(columns) && columns[i+1].start < end {
+					end = columns[i+1].start
+				}
+				value := ""
+				if col.start < end {
+					value = strings.TrimSpace(string(line[col.start:end]))
+				}
+				obj[col.name] = value
+			}
+			results = append(results, obj)
+		}
+
+		return results, scanner.Err()
+	})
+}
+
+type tableColumn struct {
+	name string
+	// offset in runes
+	start int
+}
+
+func tableColumns(header string) []tableColumn {
+	sep := tableHeaderSep
+	if !sep.MatchString(strings.TrimSpace(header)) {
+		sep = regexp.MustCompile(`\s+`)
+	}
+
+	var columns []tableColumn
+
+	offset := 0
+	for offset < len(header) {
+		// skip leading whitespace
+		rest := header[offset:]
+		trimmed := strings.TrimLeft(rest, " \t")
+		offset += len(rest) - len(trimmed)
+		if trimmed == "" {
+			break
+		}
+
+		name := trimmed
+		if loc := sep.FindStringIndex(trimmed); loc != nil {
+			name = trimmed[0:loc[0]]
+		}
+
+		columns = append(columns, tableColumn{
+			name:  name,
+			start: len([]rune(header[0:offset])),
+		})
+		offset += len(name)
+	}
+
+	return columns
+}
+
+// ParseRegex parses lines matching pattern into objects keyed by the
+// names of its capture groups.  Lines which don't match are skipped.
+// The pattern is expanded with the current vars.
+func ParseRegex(pattern string) Pipeline {
+	return NewPipeline(func(r *bufio.Reader, e gestalt.Evaluator) ([]PipeObject, error) {
+		results := make([]PipeObject, 0)
+
+		re, err := regexp.Compile(vars.Expand(e.Vars(), pattern))
+		if err != nil {
+			return results, err
+		}
+
+		scanner := bufio.NewScanner(r)
+
+		for scanner.Scan() {
+			m := re.FindStringSubmatch(scanner.Text())
+			if m == nil {
+				continue
+			}
+			obj := make(PipeObject)
+			for i, name := range re.SubexpNames() {
+				if name != "" {
+					obj[name] = m[i]
+				}
+			}
+			results = append(results, obj)
+		}
+
+		return results, scanner.Err()
+	})
+}
+
+// ParseJSONLines parses one JSON object per line (NDJSON).  Nested
+// values are flattened into dotted keys: {"a":{"b":[1]}} becomes a.b.0.
+func ParseJSONLines() Pipeline {
+	return NewPipeline(func(r *bufio.Reader, e gestalt.Evaluator) ([]PipeObject, error) {
+		results := make([]PipeObject, 0)
+
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(nil, maxJSONLine)
+
+		for lineno := 1; scanner.Scan(); lineno++ {
+			line := bytes.TrimSpace(scanner.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+
+			dec := json.NewDecoder(bytes.NewReader(line))
+			dec.UseNumber()
+
+			var value map[string]interface{}
+			if err := dec.Decode(&value); err != nil {
+				return results, fmt.Errorf("line %v: %v", lineno, err)
+			}
+
+			obj := make(PipeObject)
+			flattenJSON(obj, "", value)
+			results = append(results, obj)
+		}
+
+		return results, scanner.Err()
+	})
+}
+
+func flattenJSON(obj PipeObject, prefix string, value interface{}) {
+	key := func(k string) string {
+		if prefix == "" {
+			return k
+		}
+		return prefix + "." + k
+	}
+
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for k, v := range value {
+			flattenJSON(obj, key(k), v)
+		}
+	case []interface{}:
+		for i, v := range value {
+			flattenJSON(obj, key(strconv.Itoa(i)), v)
+		}
+	case nil:
+		obj[prefix] = ""
+	default:
+		obj[prefix] = fmt.Sprint(value)
+	}
+}
@@ -2,7 +2,6 @@ package exec
 
 import (
 	"bufio"
-	"fmt"
 	"strings"
 
 	"github.com/ovrclk/gestalt"
@@ -12,13 +11,33 @@ import (
 type Pipeline interface {
 	Capture(...string) CmdFn
 	CaptureAll() CmdFn
+	CaptureList(...string) CmdFn
 	Done() CmdFn
 
 	GrepField(string, string) Pipeline
+	GrepFieldNot(string, string) Pipeline
 	GrepWith(PipeFilter) Pipeline
+	GrepExpr(string) Pipeline
+
+	Map(PipeMapper) Pipeline
+	SortBy(...string) Pipeline
+	Unique(...string) Pipeline
+	First() Pipeline
+	Last() Pipeline
+	Nth(int) Pipeline
+	Select(...string) Pipeline
+	Rename(string, string) Pipeline
 
 	EnsureCount(int) Pipeline
+	EnsureMin(int) Pipeline
+	EnsureMax(int) Pipeline
+	EnsureEmpty() Pipeline
+	EnsureAll(PipeFilter) Pipeline
+	EnsureAny(PipeFilter) Pipeline
+	EnsureFieldMatches(string, string) Pipeline
 	EnsureWith(PipeValidator) Pipeline
+	EnsureExpr(string) Pipeline
+	EnsureAllExpr(string) Pipeline
 }
 
 type PipeObject map[string]string
@@ -26,6 +45,7 @@ type PipeStage func([]PipeObject, gestalt.Evaluator) ([]PipeObject, error)
 type PipeParser func(*bufio.Reader, gestalt.Evaluator) ([]PipeObject, error)
 type PipeFilter func(PipeObject, gestalt.Evaluator) bool
 type PipeValidator func([]PipeObject) error
+type PipeMapper func(PipeObject, gestalt.Evaluator) PipeObject
 
 type pipeline struct {
 	pipe    []PipeStage
@@ -80,7 +100,7 @@ func LineParser(fn func(string, gestalt.Evaluator) (PipeObject, error)) Pipeline
 func (p *pipeline) EnsureCount(count int) Pipeline {
 	return p.EnsureWith(func(objs []PipeObject) error {
 		if len(objs) != count {
-			return fmt.Errorf("invalid count have:%v want:%v", len(objs), count)
+			return pipeError(objs, "invalid count have:%v want:%v", len(objs), count)
 		}
 		return nil
 	})
@@ -141,6 +161,23 @@ func (p *pipeline) CaptureAll() CmdFn {
 	})
 }
 
+// CaptureList stores the values of each key from every object into a
+// list (see vars.PutList).
+func (p *pipeline) CaptureList(keys ...string) CmdFn {
+	return p.finally(func(objs []PipeObject, e gestalt.Evaluator) error {
+		for _, k := range keys {
+			values := make([]string, 0, len(objs))
+			for _, obj := range objs {
+				if v, ok := obj[k]; ok {
+					values = append(values, v)
+				}
+			}
+			vars.PutList(e.Vars(), k, values)
+		}
+		return nil
+	})
+}
+
 func (p *pipeline) Then(fn PipeStage) *pipeline {
 	p.pipe = append(p.pipe, fn)
 	return p
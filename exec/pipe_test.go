@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"strings"
 	"testing"
 
 	"github.com/ovrclk/gestalt"
@@ -114,6 +115,241 @@ func TestExpand(t *testing.T) {
 
 }
 
+func TestParseTable(t *testing.T) {
+	e := newEvaluator(t)
+
+	b := bytes.NewBufferString("" +
+		"CONTAINER ID   IMAGE          COMMAND                  PORTS      NAMES\n" +
+		"4c01db0b339c   ubuntu:12.04   \"bash\"                   8080/tcp   web\n" +
+		"d7886598dbe2   crosbymichael  \"sh -c 'sleep 1'\"                   db two\n" +
+		"\n")
+
+	p := exec.ParseTable().
+		GrepField("NAMES", "db two").
+		EnsureCount(1)
+
+	err := p.CaptureAll()(bufio.NewReader(b), e)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "d7886598dbe2", e.Vars().Get("CONTAINER ID"))
+	assert.Equal(t, "crosbymichael", e.Vars().Get("IMAGE"))
+	assert.Equal(t, `"sh -c 'sleep 1'"`, e.Vars().Get("COMMAND"))
+	assert.Equal(t, "", e.Vars().Get("PORTS"))
+	assert.True(t, e.Vars().Has("PORTS"))
+}
+
+func TestParseTable_singleSpaced(t *testing.T) {
+	e := newEvaluator(t)
+	b := bytes.NewBufferString("NAME READY\nfoo  1/1\n")
+
+	err := exec.ParseTable().EnsureCount(1).CaptureAll()(bufio.NewReader(b), e)
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", e.Vars().Get("NAME"))
+	assert.Equal(t, "1/1", e.Vars().Get("READY"))
+}
+
+func TestParseRegex(t *testing.T) {
+	e := newEvaluator(t)
+	e.vars.Put("level", "INFO")
+
+	b := bytes.NewBufferString("" +
+		"2019-10-01 INFO created user=u1 id=1\n" +
+		"some noise\n" +
+		"2019-10-01 WARN created user=u2 id=2\n" +
+		"2019-10-01 INFO created user=u3 id=3\n")
+
+	p := exec.ParseRegex(`{{level}} created user=(?P<user>\S+) id=(?P<id>\d+)`).
+		EnsureCount(2).
+		GrepField("user", "u3")
+
+	err := p.CaptureAll()(bufio.NewReader(b), e)
+	assert.NoError(t, err)
+	assert.Equal(t, "u3", e.Vars().Get("user"))
+	assert.Equal(t, "3", e.Vars().Get("id"))
+}
+
+func TestParseJSONLines(t *testing.T) {
+	e := newEvaluator(t)
+
+	b := bytes.NewBufferString("" +
+		`{"name": "u1", "meta": {"id": 10, "admin": false}, "tags": ["a", "b"], "x": null}` + "\n" +
+		"\n" +
+		`{"name": "u2", "meta": {"id": 12345678901234, "admin": true}}` + "\n")
+
+	p := exec.ParseJSONLines().
+		GrepField("meta.admin", "true").
+		EnsureCount(1)
+
+	err := p.CaptureAll()(bufio.NewReader(b), e)
+	assert.NoError(t, err)
+	assert.Equal(t, "u2", e.Vars().Get("name"))
+	assert.Equal(t, "12345678901234", e.Vars().Get("meta.id"))
+
+	e = newEvaluator(t)
+	b = bytes.NewBufferString(`{"name": "u1", "tags": ["a", "b"], "x": null}` + "\n")
+	err = exec.ParseJSONLines().CaptureAll()(bufio.NewReader(b), e)
+	assert.NoError(t, err)
+	assert.Equal(t, "b", e.Vars().Get("tags.1"))
+	assert.True(t, e.Vars().Has("x"))
+
+	b = bytes.NewBufferString("{\"a\": 1}\nnot json\n")
+	err = exec.ParseJSONLines().Done()(bufio.NewReader(b), newEvaluator(t))
+	assert.Error(t, err)
+}
+
+func runPipeline(t *testing.T, p exec.Pipeline, input string) ([]exec.PipeObject, error) {
+	var objs []exec.PipeObject
+	err := p.EnsureWith(func(result []exec.PipeObject) error {
+		objs = result
+		return nil
+	}).Done()(bufio.NewReader(bytes.NewBufferString(input)), newEvaluator(t))
+	return objs, err
+}
+
+const users = "u3 10 admin\nu1 9 user\nu2 10 user\nu1 9 user\n"
+
+func TestTransforms(t *testing.T) {
+	objs, err := runPipeline(t, exec.ParseColumns("name", "id", "role").
+		Unique().
+		SortBy("id", "name").
+		Select("name", "id").
+		Rename("name", "user"), users)
+	assert.NoError(t, err)
+	assert.Equal(t, []exec.PipeObject{
+		{"user": "u1", "id": "9"},
+		{"user": "u2", "id": "10"},
+		{"user": "u3", "id": "10"},
+	}, objs)
+
+	objs, err = runPipeline(t, exec.ParseColumns("name", "id").Unique("id").Last(), users)
+	assert.NoError(t, err)
+	assert.Equal(t, []exec.PipeObject{{"name": "u1", "id": "9"}}, objs)
+
+	objs, err = runPipeline(t, exec.ParseColumns("name").First(), users)
+	assert.NoError(t, err)
+	assert.Equal(t, []exec.PipeObject{{"name": "u3"}}, objs)
+
+	objs, err = runPipeline(t, exec.ParseColumns("name").Nth(10), users)
+	assert.NoError(t, err)
+	assert.Empty(t, objs)
+
+	objs, err = runPipeline(t, exec.ParseColumns("name", "id", "role").
+		GrepFieldNot("role", "user").
+		Map(func(obj exec.PipeObject, _ gestalt.Evaluator) exec.PipeObject {
+			return exec.PipeObject{"name": strings.ToUpper(obj["name"])}
+		}), users)
+	assert.NoError(t, err)
+	assert.Equal(t, []exec.PipeObject{{"name": "U3"}}, objs)
+}
+
+func TestAssertions(t *testing.T) {
+	columns := func() exec.Pipeline { return exec.ParseColumns("name", "id", "role") }
+	isAdmin := func(obj exec.PipeObject, _ gestalt.Evaluator) bool {
+		return obj["role"] == "admin"
+	}
+
+	_, err := runPipeline(t, columns().
+		EnsureMin(4).
+		EnsureMax(4).
+		EnsureAny(isAdmin).
+		EnsureFieldMatches("id", `^\d+$`).
+		GrepField("role", "guest").
+		EnsureEmpty(), users)
+	assert.NoError(t, err)
+
+	_, err = runPipeline(t, columns().EnsureMin(5), users)
+	assert.EqualError(t, err, "invalid count have:4 want at least:5\n"+
+		`  {id="10" name="u3" role="admin"}`+"\n"+
+		`  {id="9" name="u1" role="user"}`+"\n"+
+		`  {id="10" name="u2" role="user"}`+"\n"+
+		`  {id="9" name="u1" role="user"}`)
+
+	_, err = runPipeline(t, columns().EnsureMax(3), users)
+	assert.Error(t, err)
+
+	_, err = runPipeline(t, columns().EnsureEmpty(), users)
+	assert.Error(t, err)
+
+	_, err = runPipeline(t, columns().GrepField("role", "user").EnsureAny(isAdmin), users)
+	assert.Error(t, err)
+
+	_, err = runPipeline(t, columns().EnsureAll(isAdmin), users)
+	assert.EqualError(t, err, "3 of 4 rows failed condition\n"+
+		`  {id="9" name="u1" role="user"}`+"\n"+
+		`  {id="10" name="u2" role="user"}`+"\n"+
+		`  {id="9" name="u1" role="user"}`)
+
+	_, err = runPipeline(t, columns().EnsureFieldMatches("name", "^u[12]$"), users)
+	assert.EqualError(t, err, `1 of 4 rows have name not matching "^u[12]$"`+"\n"+
+		`  {id="10" name="u3" role="admin"}`)
+}
+
+func TestExpressions(t *testing.T) {
+	columns := func() exec.Pipeline { return exec.ParseColumns("name", "id", "role") }
+
+	objs, err := runPipeline(t, columns().
+		GrepExpr("role == 'user' && int(id) < 10 || name == \"u3\""), users)
+	assert.NoError(t, err)
+	assert.Len(t, objs, 3)
+
+	objs, err = runPipeline(t, columns().
+		GrepExpr("!(id % 2 == 0) && matches(name, '^u[0-9]$') && len(role) == 4"), users)
+	assert.NoError(t, err)
+	assert.Len(t, objs, 2)
+
+	_, err = runPipeline(t, columns().
+		EnsureExpr("count > 0 && count - 1 == 3").
+		EnsureAllExpr("contains(role, 'er') || field('role') == 'admin'"), users)
+	assert.NoError(t, err)
+
+	_, err = runPipeline(t, columns().GrepField("role", "guest").EnsureExpr("count > 0"), users)
+	assert.EqualError(t, err, `expression "count > 0" is false`)
+
+	_, err = runPipeline(t, columns().EnsureAllExpr("id < 10"), users)
+	assert.EqualError(t, err, `2 of 4 rows failed expression "id < 10"`+"\n"+
+		`  {id="10" name="u3" role="admin"}`+"\n"+
+		`  {id="10" name="u2" role="user"}`)
+
+	_, err = runPipeline(t, columns().GrepExpr("int(name) > 1"), users)
+	assert.EqualError(t, err, `expression "int(name) > 1": "u3" is not a number`+"\n"+
+		`  {id="10" name="u3" role="admin"}`)
+
+	_, err = runPipeline(t, columns().GrepExpr("missing == 1"), users)
+	assert.Contains(t, err.Error(), "undefined: missing")
+
+	_, err = runPipeline(t, columns().GrepExpr("name"), users)
+	assert.Contains(t, err.Error(), "is not a boolean")
+
+	for _, bad := range []string{"(name == 'u1'", "name ==", "name == 'u1", "name # 1", "f(1"} {
+		_, err = runPipeline(t, columns().GrepExpr(bad), users)
+		assert.Error(t, err, bad)
+	}
+
+	e := newEvaluator(t)
+	e.Vars().Put("role", "admin")
+	err = columns().
+		GrepExpr("role == '{{role}}'").
+		EnsureExpr("count == 1").
+		Done()(bufio.NewReader(bytes.NewBufferString(users)), e)
+	assert.NoError(t, err)
+}
+
+func TestCaptureList(t *testing.T) {
+	e := newEvaluator(t)
+	err := exec.ParseColumns("name", "id", "role").
+		GrepField("role", "user").
+		CaptureList("name", "missing")(bufio.NewReader(bytes.NewBufferString(users)), e)
+	assert.NoError(t, err)
+
+	names, err := vars.GetList(e.Vars(), "name")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"u1", "u2", "u1"}, names)
+
+	missing, err := vars.GetList(e.Vars(), "missing")
+	assert.NoError(t, err)
+	assert.Empty(t, missing)
+}
+
 func newEvaluator(t *testing.T) *fakeEvaluator {
 	return &fakeEvaluator{t, vars.NewVars()}
 }
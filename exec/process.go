@@ -0,0 +1,86 @@
+package exec
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"runtime"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/ovrclk/gestalt"
+)
+
+// DefaultKillGrace is the time given to a cancelled command to exit
+// after SIGTERM before its process group is killed.
+var DefaultKillGrace = 5 * time.Second
+
+// run the command in its own process group so that all of its
+// descendants can be signalled together.
+func setProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+// terminate the process group of a started cmd once ctx is done:
+// SIGTERM first, then SIGKILL if the group is still around after grace.
+// The returned function must be called after the command exits; it
+// returns true if termination was started.
+func killOnCancel(ctx context.Context, cmd *exec.Cmd, grace time.Duration) func() bool {
+	donech := make(chan struct{})
+	pgid := cmd.Process.Pid
+	var killed int32
+
+	go func() {
+		select {
+		case <-donech:
+			return
+		case <-ctx.Done():
+		}
+
+		atomic.StoreInt32(&killed, 1)
+		syscall.Kill(-pgid, syscall.SIGTERM)
+
+		select {
+		case <-donech:
+			return
+		case <-time.After(grace):
+		}
+
+		syscall.Kill(-pgid, syscall.SIGKILL)
+	}()
+
+	return func() bool {
+		close(donech)
+		return atomic.LoadInt32(&killed) == 1
+	}
+}
+
+func killProcessGroup(cmd *exec.Cmd) {
+	syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
+
+// resource usage of an exited process.
+func processUsage(state *os.ProcessState) *gestalt.Usage {
+	usage := &gestalt.Usage{
+		UserCPU:   state.UserTime(),
+		SystemCPU: state.SystemTime(),
+	}
+
+	if rusage, ok := state.SysUsage().(*syscall.Rusage); ok {
+		usage.MaxRSS = int64(rusage.Maxrss)
+		// reported in kilobytes everywhere but darwin.
+		if runtime.GOOS != "darwin" {
+			usage.MaxRSS *= 1024
+		}
+	}
+
+	if wstatus, ok := state.Sys().(syscall.WaitStatus); ok && wstatus.Signaled() {
+		usage.Signal = wstatus.Signal().String()
+	}
+
+	return usage
+}
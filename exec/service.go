@@ -0,0 +1,295 @@
+package exec
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ovrclk/gestalt"
+	"github.com/ovrclk/gestalt/vars"
+)
+
+// DefaultReadyTimeout is the time a Service waits for its readiness
+// conditions unless overridden with ReadyTimeout.
+var DefaultReadyTimeout = 30 * time.Second
+
+// Service starts a long-running command in the background and blocks
+// until all of its readiness conditions hold.
+type Service interface {
+	gestalt.CompositeComponent
+
+	WaitOutput(pattern string, emit ...string) Service
+	WaitTCP(addr string) Service
+	WaitHTTP(url string) Service
+	WaitFile(path string) Service
+
+	PID(key string) Service
+	ReadyTimeout(time.Duration) Service
+	PollInterval(time.Duration) Service
+}
+
+type readyCheck func(e gestalt.Evaluator, w *cmdWatch) (bool, error)
+
+type service struct {
+	cmp      gestalt.Component
+	child    Cmd
+	cmd      *cmd
+	err      error
+	checks   []readyCheck
+	pidKey   string
+	exports  []string
+	timeout  time.Duration
+	interval time.Duration
+}
+
+func NewService(name string, c Cmd) Service {
+	s := &service{
+		cmp:      gestalt.NewComponent(name, nil),
+		child:    c,
+		timeout:  DefaultReadyTimeout,
+		interval: time.Second / 10,
+	}
+	if cmd, ok := c.(*cmd); ok {
+		s.cmd = cmd
+	} else {
+		s.err = fmt.Errorf("can't run %T as a service", c)
+	}
+	return s
+}
+
+func (c *service) Name() string {
+	return c.cmp.Name()
+}
+
+func (c *service) IsPassThrough() bool {
+	return false
+}
+
+func (c *service) WithMeta(m vars.Meta) gestalt.Component {
+	c.cmp.WithMeta(m)
+	return c
+}
+
+// variables set once the service is ready are exported.
+func (c *service) Meta() vars.Meta {
+	return vars.NewMeta().Merge(c.cmp.Meta()).Export(c.exports...)
+}
+
+func (c *service) Children() []gestalt.Component {
+	return []gestalt.Component{c.child}
+}
+
+// WaitOutput waits for a line of stdout or stderr matching pattern.
+// The groups of the match are stored into the emit variables, in order,
+// which are exported.
+func (c *service) WaitOutput(pattern string, emit ...string) Service {
+	for _, key := range emit {
+		if key != "" {
+			c.exports = append(c.exports, key)
+		}
+	}
+	c.checks = append(c.checks, func(e gestalt.Evaluator, w *cmdWatch) (bool, error) {
+		re, err := regexp.Compile(vars.Expand(e.Vars(), pattern))
+		if err != nil {
+			return false, err
+		}
+		for _, line := range w.lines() {
+			m := re.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			for i, key := range emit {
+				if key != "" && i+1 < len(m) {
+					e.Vars().Put(key, m[i+1])
+				}
+			}
+			return true, nil
+		}
+		return false, nil
+	})
+	return c
+}
+
+// WaitTCP waits until addr (host:port) accepts connections.
+func (c *service) WaitTCP(addr string) Service {
+	c.checks = append(c.checks, func(e gestalt.Evaluator, _ *cmdWatch) (bool, error) {
+		conn, err := net.DialTimeout("tcp", vars.Expand(e.Vars(), addr), c.interval)
+		if err != nil {
+			return false, nil
+		}
+		conn.Close()
+		return true, nil
+	})
+	return c
+}
+
+// WaitHTTP waits until a GET of url returns 200.  Each request is
+// given at most the poll interval.
+func (c *service) WaitHTTP(url string) Service {
+	c.checks = append(c.checks, func(e gestalt.Evaluator, _ *cmdWatch) (bool, error) {
+		req, err := http.NewRequest("GET", vars.Expand(e.Vars(), url), nil)
+		if err != nil {
+			return false, err
+		}
+		client := &http.Client{Timeout: c.interval}
+		resp, err := client.Do(req.WithContext(e.Context()))
+		if err != nil {
+			return false, nil
+		}
+		resp.Body.Close()
+		return resp.StatusCode == http.StatusOK, nil
+	})
+	return c
+}
+
+// WaitFile waits until path exists.
+func (c *service) WaitFile(path string) Service {
+	c.checks = append(c.checks, func(e gestalt.Evaluator, _ *cmdWatch) (bool, error) {
+		_, err := os.Stat(vars.Expand(e.Vars(), path))
+		return err == nil, nil
+	})
+	return c
+}
+
+// PID stores the process id of the command into key once it is ready,
+// and exports it.  Replayed commands have no process and store 0.
+func (c *service) PID(key string) Service {
+	c.pidKey = key
+	c.exports = append(c.exports, key)
+	return c
+}
+
+func (c *service) ReadyTimeout(timeout time.Duration) Service {
+	c.timeout = timeout
+	return c
+}
+
+func (c *service) PollInterval(interval time.Duration) Service {
+	c.interval = interval
+	return c
+}
+
+func (c *service) Eval(e gestalt.Evaluator) error {
+	if c.err != nil {
+		return c.err
+	}
+
+	w := newCmdWatch()
+	e.Fork(&watchedCmd{c.cmd, w})
+
+	if err := c.waitReady(e, w); err != nil {
+		// don't leave the command running.
+		e.Stop()
+		e.Wait()
+		return err
+	}
+
+	if c.pidKey != "" {
+		e.Vars().Put(c.pidKey, strconv.Itoa(w.pid()))
+	}
+
+	e.Message("[ready]")
+	return nil
+}
+
+func (c *service) waitReady(e gestalt.Evaluator, w *cmdWatch) error {
+	deadline := time.After(c.timeout)
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	pending := c.checks
+	started := false
+	for {
+		select {
+		case <-w.startch:
+			started = true
+			next := pending[:0:0]
+			for _, check := range pending {
+				ok, err := check(e, w)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					next = append(next, check)
+				}
+			}
+			pending = next
+		default:
+		}
+
+		if len(pending) == 0 && started {
+			return nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-w.exitch:
+			return fmt.Errorf("exited before ready")
+		case <-deadline:
+			return fmt.Errorf("not ready after %v", c.timeout)
+		case <-e.Context().Done():
+			return e.Context().Err()
+		}
+	}
+}
+
+// watchedCmd evaluates a cmd in its place, reporting to a cmdWatch.
+type watchedCmd struct {
+	*cmd
+	watch *cmdWatch
+}
+
+func (c *watchedCmd) Eval(e gestalt.Evaluator) error {
+	return c.cmd.eval(e, c.watch)
+}
+
+// cmdWatch observes a running command on behalf of a Service.
+type cmdWatch struct {
+	mtx     sync.Mutex
+	pidv    int
+	output  []string
+	startch chan struct{}
+	exitch  chan struct{}
+}
+
+func newCmdWatch() *cmdWatch {
+	return &cmdWatch{
+		startch: make(chan struct{}),
+		exitch:  make(chan struct{}),
+	}
+}
+
+func (w *cmdWatch) started(pid int) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	w.pidv = pid
+	close(w.startch)
+}
+
+func (w *cmdWatch) line(line string) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	w.output = append(w.output, strings.TrimRight(line, "\r\n"))
+}
+
+func (w *cmdWatch) exited() {
+	close(w.exitch)
+}
+
+func (w *cmdWatch) pid() int {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	return w.pidv
+}
+
+func (w *cmdWatch) lines() []string {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	return append([]string(nil), w.output...)
+}
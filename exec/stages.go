@@ -0,0 +1,239 @@
+package exec
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ovrclk/gestalt"
+	"github.com/ovrclk/gestalt/vars"
+)
+
+// GrepFieldNot keeps objects whose key is missing or differs from value.
+func (p *pipeline) GrepFieldNot(key string, value string) Pipeline {
+	return p.GrepWith(func(obj PipeObject, e gestalt.Evaluator) bool {
+		v, ok := obj[key]
+		return !ok || v != vars.Expand(e.Vars(), value)
+	})
+}
+
+func (p *pipeline) Map(fn PipeMapper) Pipeline {
+	return p.Then(func(objs []PipeObject, e gestalt.Evaluator) ([]PipeObject, error) {
+		result := make([]PipeObject, 0, len(objs))
+		for _, obj := range objs {
+			result = append(result, fn(obj, e))
+		}
+		return result, nil
+	})
+}
+
+// SortBy orders objects by the given keys.  Values which are both
+// numbers are compared numerically.
+func (p *pipeline) SortBy(keys ...string) Pipeline {
+	return p.Then(func(objs []PipeObject, _ gestalt.Evaluator) ([]PipeObject, error) {
+		result := append([]PipeObject(nil), objs...)
+		sort.SliceStable(result, func(i, j int) bool {
+			for _, k := range keys {
+				if c := compareValues(result[i][k], result[j][k]); c != 0 {
+					return c < 0
+				}
+			}
+			return false
+		})
+		return result, nil
+	})
+}
+
+// Unique drops objects whose values for keys (all keys if none are
+// given) equal those of an earlier object.
+func (p *pipeline) Unique(keys ...string) Pipeline {
+	return p.Then(func(objs []PipeObject, _ gestalt.Evaluator) ([]PipeObject, error) {
+		seen := make(map[string]bool)
+		result := make([]PipeObject, 0)
+		for _, obj := range objs {
+			fields := obj
+			if len(keys) > 0 {
+				fields = obj.Select(keys...)
+			}
+			if id := fmtObject(fields); !seen[id] {
+				seen[id] = true
+				result = append(result, obj)
+			}
+		}
+		return result, nil
+	})
+}
+
+func (p *pipeline) First() Pipeline {
+	return p.Nth(0)
+}
+
+func (p *pipeline) Last() Pipeline {
+	return p.Nth(-1)
+}
+
+// Nth keeps only the object at index n; negative indices count from
+// the end.  No objects remain if n is out of range.
+func (p *pipeline) Nth(n int) Pipeline {
+	return p.Then(func(objs []PipeObject, _ gestalt.Evaluator) ([]PipeObject, error) {
+		idx := n
+		if idx < 0 {
+			idx += len(objs)
+		}
+		if idx < 0 || idx >= len(objs) {
+			return []PipeObject{}, nil
+		}
+		return []PipeObject{objs[idx]}, nil
+	})
+}
+
+func (p *pipeline) Select(keys ...string) Pipeline {
+	return p.Map(func(obj PipeObject, _ gestalt.Evaluator) PipeObject {
+		return obj.Select(keys...)
+	})
+}
+
+func (p *pipeline) Rename(from, to string) Pipeline {
+	return p.Map(func(obj PipeObject, _ gestalt.Evaluator) PipeObject {
+		v, ok := obj[from]
+		if !ok {
+			return obj
+		}
+		result := make(PipeObject)
+		for k, v := range obj {
+			result[k] = v
+		}
+		delete(result, from)
+		result[to] = v
+		return result
+	})
+}
+
+func (p *pipeline) EnsureMin(count int) Pipeline {
+	return p.EnsureWith(func(objs []PipeObject) error {
+		if len(objs) < count {
+			return pipeError(objs, "invalid count have:%v want at least:%v", len(objs), count)
+		}
+		return nil
+	})
+}
+
+func (p *pipeline) EnsureMax(count int) Pipeline {
+	return p.EnsureWith(func(objs []PipeObject) error {
+		if len(objs) > count {
+			return pipeError(objs, "invalid count have:%v want at most:%v", len(objs), count)
+		}
+		return nil
+	})
+}
+
+func (p *pipeline) EnsureEmpty() Pipeline {
+	return p.EnsureMax(0)
+}
+
+// EnsureAll fails unless every object satisfies fn.
+func (p *pipeline) EnsureAll(fn PipeFilter) Pipeline {
+	return p.Then(func(objs []PipeObject, e gestalt.Evaluator) ([]PipeObject, error) {
+		failed := make([]PipeObject, 0)
+		for _, obj := range objs {
+			if !fn(obj, e) {
+				failed = append(failed, obj)
+			}
+		}
+		if len(failed) > 0 {
+			return objs, pipeError(failed, "%v of %v rows failed condition", len(failed), len(objs))
+		}
+		return objs, nil
+	})
+}
+
+// EnsureAny fails unless at least one object satisfies fn.
+func (p *pipeline) EnsureAny(fn PipeFilter) Pipeline {
+	return p.Then(func(objs []PipeObject, e gestalt.Evaluator) ([]PipeObject, error) {
+		for _, obj := range objs {
+			if fn(obj, e) {
+				return objs, nil
+			}
+		}
+		return objs, pipeError(objs, "no rows matched condition")
+	})
+}
+
+// EnsureFieldMatches fails unless the value of key matches pattern
+// in every object.
+func (p *pipeline) EnsureFieldMatches(key string, pattern string) Pipeline {
+	return p.Then(func(objs []PipeObject, e gestalt.Evaluator) ([]PipeObject, error) {
+		expanded := vars.Expand(e.Vars(), pattern)
+		re, err := regexp.Compile(expanded)
+		if err != nil {
+			return objs, err
+		}
+		failed := make([]PipeObject, 0)
+		for _, obj := range objs {
+			if v, ok := obj[key]; !ok || !re.MatchString(v) {
+				failed = append(failed, obj)
+			}
+		}
+		if len(failed) > 0 {
+			return objs, pipeError(failed, "%v of %v rows have %v not matching %q",
+				len(failed), len(objs), key, expanded)
+		}
+		return objs, nil
+	})
+}
+
+// Select returns a copy of obj with only the given keys.
+func (obj PipeObject) Select(keys ...string) PipeObject {
+	result := make(PipeObject)
+	for _, k := range keys {
+		if v, ok := obj[k]; ok {
+			result[k] = v
+		}
+	}
+	return result
+}
+
+func (obj PipeObject) String() string {
+	return fmtObject(obj)
+}
+
+// error listing the offending objects.
+func pipeError(objs []PipeObject, format string, args ...interface{}) error {
+	buf := bytes.NewBufferString(fmt.Sprintf(format, args...))
+	for _, obj := range objs {
+		fmt.Fprintf(buf, "\n  %v", fmtObject(obj))
+	}
+	return fmt.Errorf("%v", buf.String())
+}
+
+func fmtObject(obj PipeObject) string {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	fields := make([]string, 0, len(keys))
+	for _, k := range keys {
+		fields = append(fields, fmt.Sprintf("%v=%q", k, obj[k]))
+	}
+	return "{" + strings.Join(fields, " ") + "}"
+}
+
+func compareValues(a, b string) int {
+	fa, erra := strconv.ParseFloat(a, 64)
+	fb, errb := strconv.ParseFloat(b, 64)
+	if erra == nil && errb == nil {
+		switch {
+		case fa < fb:
+			return -1
+		case fa > fb:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(a, b)
+}
@@ -0,0 +1,145 @@
+package gestalt
+
+import (
+	"regexp"
+	"strings"
+)
+
+// focusHandler skips components which are excluded by the
+// skip patterns or aren't selected by the only patterns.
+//
+// Ancestors of selected components are run, as are the
+// fixtures (see FixtureComponent) of any component that runs.
+//
+// Patterns without an index (see NewForEach) select every iteration.
+type focusHandler struct {
+	only []string
+	skip []string
+
+	// forced fixture paths
+	forced map[string]int
+
+	// only patterns without indexes.
+	unindexed []string
+
+	// depth of selected subtrees currently being evaluated
+	focused int
+
+	next evalHandler
+}
+
+func newFocusHandler(only []string, skip []string, next evalHandler) *focusHandler {
+	var unindexed []string
+	for _, p := range only {
+		unindexed = append(unindexed, stripIndexes(p))
+	}
+	return &focusHandler{
+		only:      only,
+		skip:      skip,
+		unindexed: unindexed,
+		forced:    make(map[string]int),
+		next:      next,
+	}
+}
+
+func (h *focusHandler) Eval(e Evaluator, node Component) error {
+	path := e.Path()
+
+	if matchIndexedPath(path, h.skip) >= 0 {
+		return h.skipNode(e)
+	}
+
+	if len(h.only) == 0 || h.focused > 0 {
+		return h.next.Eval(e, node)
+	}
+
+	if h.forced[path] > 0 || matchIndexedPath(path, h.only) >= 0 {
+		h.focused++
+		defer func() { h.focused-- }()
+		return h.next.Eval(e, node)
+	}
+
+	paths := evalPaths(e, node)
+
+	if !h.containsMatch(paths, node) {
+		return h.skipNode(e)
+	}
+
+	if fc, ok := node.(FixtureComponent); ok {
+		for _, child := range fc.Fixtures() {
+			fpath := paths.Child(child.Name())
+			h.forced[fpath]++
+			defer h.release(fpath)
+		}
+	}
+
+	return h.next.Eval(e, node)
+}
+
+// forks continue from the state of the forking evaluator.
+func (h *focusHandler) fork() evalHandler {
+	forced := make(map[string]int, len(h.forced))
+	for path, count := range h.forced {
+		forced[path] = count
+	}
+	return &focusHandler{
+		only:      h.only,
+		skip:      h.skip,
+		unindexed: h.unindexed,
+		forced:    forced,
+		focused:   h.focused,
+		next:      forkEvalHandler(h.next),
+	}
+}
+
+func (h *focusHandler) release(path string) {
+	if h.forced[path]--; h.forced[path] <= 0 {
+		delete(h.forced, path)
+	}
+}
+
+// whether node or its descendants may be selected.  Iterations of
+// ForEach components within node aren't known yet, so their paths
+// are also matched against the patterns without indexes.
+func (h *focusHandler) containsMatch(paths *pathVisitor, node Component) bool {
+	found := false
+	traversePathsFrom(paths.Parent(), node, func(p string) {
+		if !found && (matchIndexedPath(p, h.only) >= 0 || matchPath(p, h.unindexed) >= 0) {
+			found = true
+		}
+	})
+	return found
+}
+
+// path state of the evaluation of node.
+func evalPaths(e Evaluator, node Component) *pathVisitor {
+	if pt, ok := e.(pathTracker); ok {
+		return pt.paths()
+	}
+	base := strings.TrimSuffix(e.Path(), "/"+node.Name())
+	paths := &pathVisitor{[]path{path{base: base, name: base}}}
+	paths.Push(nil, node)
+	return paths
+}
+
+var pathIndex = regexp.MustCompile(`\[\d+\]`)
+
+func stripIndexes(path string) string {
+	return pathIndex.ReplaceAllString(path, "")
+}
+
+// match path, or path without indexes, against points.
+func matchIndexedPath(path string, points []string) int {
+	if idx := matchPath(path, points); idx >= 0 {
+		return idx
+	}
+	return matchPath(stripIndexes(path), points)
+}
+
+func (h *focusHandler) skipNode(e Evaluator) error {
+	if s, ok := e.(skipper); ok {
+		s.Skip()
+	}
+	e.Message("[skipped]")
+	return nil
+}
@@ -0,0 +1,126 @@
+package gestalt_test
+
+import (
+	"testing"
+
+	"github.com/ovrclk/gestalt"
+	"github.com/ovrclk/gestalt/component"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFocus(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected []string
+	}{
+		{
+			[]string{},
+			[]string{"a", "setup", "x", "y", "teardown", "b"},
+		},
+		{
+			[]string{"--only", "g/y"},
+			[]string{"setup", "y", "teardown"},
+		},
+		{
+			[]string{"--only", "/g"},
+			[]string{"setup", "x", "y", "teardown"},
+		},
+		{
+			[]string{"--only", "b"},
+			[]string{"b"},
+		},
+		{
+			[]string{"--skip", "x", "--skip", "b"},
+			[]string{"a", "setup", "y", "teardown"},
+		},
+		{
+			[]string{"--only", "/g", "--skip", "x"},
+			[]string{"setup", "y", "teardown"},
+		},
+	}
+
+	for _, test := range tests {
+		trace := make([]string, 0)
+		tracer := func(name string) gestalt.Component {
+			return gestalt.NewComponent(name, func(_ gestalt.Evaluator) error {
+				trace = append(trace, name)
+				return nil
+			})
+		}
+
+		cmp := component.NewSuite("top").
+			Run(tracer("a")).
+			Run(component.NewEnsure("env").
+				First(tracer("setup")).
+				Run(component.NewGroup("g").
+					Run(tracer("x")).
+					Run(tracer("y"))).
+				Finally(tracer("teardown"))).
+			Run(tracer("b"))
+
+		status := 0
+		gestalt.NewRunner().
+			WithComponent(cmp).
+			WithArgs(append([]string{"eval"}, test.args...)).
+			WithTerminate(func(s int) { status = s }).
+			Run()
+
+		assert.Equal(t, 0, status, "%v", test.args)
+		assert.Equal(t, test.expected, trace, "%v", test.args)
+	}
+}
+
+func TestFocus_forEach(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected []string
+	}{
+		{
+			[]string{},
+			[]string{"setup a", "body a", "teardown a", "setup b", "body b", "teardown b", "after"},
+		},
+		{
+			[]string{"--only", "body"},
+			[]string{"setup a", "body a", "teardown a", "setup b", "body b", "teardown b"},
+		},
+		{
+			[]string{"--only", "body[1]"},
+			[]string{"setup b", "body b", "teardown b"},
+		},
+		{
+			[]string{"--skip", "body[0]"},
+			[]string{"setup a", "teardown a", "setup b", "body b", "teardown b", "after"},
+		},
+	}
+
+	for _, test := range tests {
+		trace := make([]string, 0)
+		tracer := func(name string) gestalt.Component {
+			return gestalt.NewComponent(name, func(e gestalt.Evaluator) error {
+				trace = append(trace, name+" "+e.Vars().Get("item"))
+				return nil
+			})
+		}
+
+		cmp := component.NewSuite("top").
+			Run(component.NewForEach("list", "item").
+				Run(component.NewEnsure("env").
+					First(tracer("setup")).
+					Run(tracer("body")).
+					Finally(tracer("teardown")))).
+			Run(gestalt.NewComponent("after", func(_ gestalt.Evaluator) error {
+				trace = append(trace, "after")
+				return nil
+			}))
+
+		status := 0
+		gestalt.NewRunner().
+			WithComponent(cmp).
+			WithArgs(append([]string{"eval", `-slist=["a","b"]`}, test.args...)).
+			WithTerminate(func(s int) { status = s }).
+			Run()
+
+		assert.Equal(t, 0, status, "%v", test.args)
+		assert.Equal(t, test.expected, trace, "%v", test.args)
+	}
+}
@@ -6,6 +6,7 @@ require (
 	github.com/alecthomas/template v0.0.0-20160405071501-a0175ee3bccc // indirect
 	github.com/alecthomas/units v0.0.0-20151022065526-2efee857e7cf // indirect
 	github.com/buger/jsonparser v0.0.0-20191004114745-ee4c978eae7e
+	github.com/creack/pty v1.1.11
 	github.com/deckarep/golang-set v0.0.0-20171013212420-1d4478f51bed
 	github.com/fatih/color v1.6.0
 	github.com/mattn/go-colorable v0.0.10-0.20180205070158-7dc3415be66d // indirect
@@ -13,4 +14,5 @@ require (
 	github.com/sirupsen/logrus v1.4.2
 	github.com/stretchr/testify v1.4.0
 	gopkg.in/alecthomas/kingpin.v2 v2.2.6
+	gopkg.in/yaml.v2 v2.2.2
 )
@@ -0,0 +1,282 @@
+package gestalt
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+const (
+	FormatPaths   = "paths"
+	FormatTree    = "tree"
+	FormatJSON    = "json"
+	FormatDOT     = "dot"
+	FormatMermaid = "mermaid"
+)
+
+var graphFormats = []string{FormatPaths, FormatTree, FormatJSON, FormatDOT, FormatMermaid}
+
+type graphNode struct {
+	id string
+
+	Name        string            `json:"name"`
+	Path        string            `json:"path"`
+	PassThrough bool              `json:"passthrough,omitempty"`
+	Requires    []string          `json:"requires,omitempty"`
+	Exports     []string          `json:"exports,omitempty"`
+	Defaults    map[string]string `json:"defaults,omitempty"`
+	Children    []*graphNode      `json:"children,omitempty"`
+}
+
+// variable produced by one component and required by another.
+type graphFlow struct {
+	from *graphNode
+	to   *graphNode
+	name string
+}
+
+type graph struct {
+	root  *graphNode
+	flows []graphFlow
+}
+
+// DumpFormat writes the component tree in the given format.
+func DumpFormat(w io.Writer, c Component, format string) error {
+	g := newGraphBuilder()
+	Traverse(c, g)
+
+	switch format {
+	case FormatPaths:
+		TraversePaths(c, func(path string) {
+			fmt.Fprintf(w, "%v\n", path)
+		})
+		return nil
+	case FormatTree:
+		return g.graph.writeTree(w)
+	case FormatJSON:
+		return g.graph.writeJSON(w)
+	case FormatDOT:
+		return g.graph.writeDOT(w)
+	case FormatMermaid:
+		return g.graph.writeMermaid(w)
+	}
+	return fmt.Errorf("unknown format %v", format)
+}
+
+type graphBuilder struct {
+	graph     *graph
+	stack     []*graphNode
+	count     int
+	producers map[string]*graphNode
+}
+
+func newGraphBuilder() *graphBuilder {
+	return &graphBuilder{
+		graph:     &graph{},
+		producers: make(map[string]*graphNode),
+	}
+}
+
+func (b *graphBuilder) Push(t Traverser, c Component) {
+	node := &graphNode{
+		id:          fmt.Sprintf("n%v", b.count),
+		Name:        c.Name(),
+		Path:        t.Path(),
+		PassThrough: c.IsPassThrough(),
+	}
+	b.count++
+
+	meta := c.Meta()
+	node.Requires = meta.Requires()
+	node.Exports = meta.Exports()
+
+	// wrappers include the meta of their children; only show their own.
+	if cc, ok := c.(CompositeComponent); ok && c.IsPassThrough() {
+		for _, child := range cc.Children() {
+			node.Requires = without(node.Requires, child.Meta().Requires())
+			node.Exports = without(node.Exports, child.Meta().Exports())
+		}
+	}
+
+	if defaults := meta.Defaults(); len(defaults) > 0 {
+		node.Defaults = defaults
+	}
+
+	if !node.PassThrough {
+		for _, name := range node.Requires {
+			if from, ok := b.producers[name]; ok {
+				b.graph.flows = append(b.graph.flows, graphFlow{from, node, name})
+			}
+		}
+	}
+
+	if sz := len(b.stack); sz > 0 {
+		parent := b.stack[sz-1]
+		parent.Children = append(parent.Children, node)
+	} else {
+		b.graph.root = node
+	}
+
+	b.stack = append(b.stack, node)
+}
+
+func (b *graphBuilder) Pop(_ Traverser, _ Component) {
+	sz := len(b.stack)
+	node := b.stack[sz-1]
+	b.stack = b.stack[0 : sz-1]
+
+	if !node.PassThrough {
+		for _, name := range node.Exports {
+			b.producers[name] = node
+		}
+	}
+}
+
+func (g *graph) writeTree(w io.Writer) error {
+	var walk func(*graphNode, int)
+	walk = func(node *graphNode, depth int) {
+		fmt.Fprintf(w, "%v%v", strings.Repeat("  ", depth), node.Name)
+		if node.PassThrough {
+			fmt.Fprintf(w, " (pass-through)")
+		}
+		if desc := node.describe("; "); desc != "" {
+			fmt.Fprintf(w, " [%v]", desc)
+		}
+		fmt.Fprintf(w, "\n")
+		for _, child := range node.Children {
+			walk(child, depth+1)
+		}
+	}
+	if g.root != nil {
+		walk(g.root, 0)
+	}
+	return nil
+}
+
+func (g *graph) writeJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(g.root)
+}
+
+func (g *graph) writeDOT(w io.Writer) error {
+	fmt.Fprintf(w, "digraph gestalt {\n")
+	fmt.Fprintf(w, "  node [shape=box];\n")
+
+	g.walk(func(node *graphNode) {
+		label := node.Name
+		if desc := node.describe("\n"); desc != "" {
+			label += "\n" + desc
+		}
+		style := ""
+		if node.PassThrough {
+			style = ", style=dashed"
+		}
+		fmt.Fprintf(w, "  %v [label=%v%v];\n", node.id, quoteDOT(label), style)
+	})
+
+	g.walk(func(node *graphNode) {
+		for _, child := range node.Children {
+			fmt.Fprintf(w, "  %v -> %v;\n", node.id, child.id)
+		}
+	})
+
+	for _, flow := range g.flows {
+		fmt.Fprintf(w, "  %v -> %v [label=%v, style=dotted, color=blue, constraint=false];\n",
+			flow.from.id, flow.to.id, quoteDOT(flow.name))
+	}
+
+	fmt.Fprintf(w, "}\n")
+	return nil
+}
+
+func (g *graph) writeMermaid(w io.Writer) error {
+	fmt.Fprintf(w, "graph TD\n")
+
+	g.walk(func(node *graphNode) {
+		label := node.Name
+		if desc := node.describe("<br/>"); desc != "" {
+			label += "<br/>" + desc
+		}
+		if node.PassThrough {
+			fmt.Fprintf(w, "  %v([%v])\n", node.id, quoteMermaid(label))
+		} else {
+			fmt.Fprintf(w, "  %v[%v]\n", node.id, quoteMermaid(label))
+		}
+	})
+
+	g.walk(func(node *graphNode) {
+		for _, child := range node.Children {
+			fmt.Fprintf(w, "  %v --> %v\n", node.id, child.id)
+		}
+	})
+
+	for _, flow := range g.flows {
+		fmt.Fprintf(w, "  %v -. %v .-> %v\n", flow.from.id, quoteMermaid(flow.name), flow.to.id)
+	}
+
+	return nil
+}
+
+func (g *graph) walk(fn func(*graphNode)) {
+	var walk func(*graphNode)
+	walk = func(node *graphNode) {
+		fn(node)
+		for _, child := range node.Children {
+			walk(child)
+		}
+	}
+	if g.root != nil {
+		walk(g.root)
+	}
+}
+
+func (n *graphNode) describe(sep string) string {
+	parts := make([]string, 0, 3)
+	if len(n.Requires) > 0 {
+		parts = append(parts, "requires: "+strings.Join(n.Requires, ", "))
+	}
+	if len(n.Exports) > 0 {
+		parts = append(parts, "exports: "+strings.Join(n.Exports, ", "))
+	}
+	if len(n.Defaults) > 0 {
+		keys := make([]string, 0, len(n.Defaults))
+		for k := range n.Defaults {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		defaults := make([]string, 0, len(keys))
+		for _, k := range keys {
+			defaults = append(defaults, k+"="+n.Defaults[k])
+		}
+		parts = append(parts, "defaults: "+strings.Join(defaults, ", "))
+	}
+	return strings.Join(parts, sep)
+}
+
+func without(values []string, remove []string) []string {
+	result := make([]string, 0, len(values))
+outer:
+	for _, v := range values {
+		for _, r := range remove {
+			if v == r {
+				continue outer
+			}
+		}
+		result = append(result, v)
+	}
+	return result
+}
+
+func quoteDOT(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `"`, `\"`, -1)
+	s = strings.Replace(s, "\n", `\n`, -1)
+	return `"` + s + `"`
+}
+
+func quoteMermaid(s string) string {
+	return `"` + strings.Replace(s, `"`, "#quot;", -1) + `"`
+}
@@ -0,0 +1,75 @@
+package gestalt_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/ovrclk/gestalt"
+	"github.com/ovrclk/gestalt/component"
+	"github.com/ovrclk/gestalt/vars"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func graphComponent() gestalt.Component {
+	producer := gestalt.NewComponent("producer", nil).
+		WithMeta(vars.NewMeta().Export("host"))
+	consumer := gestalt.NewComponent("consumer", nil).
+		WithMeta(vars.NewMeta().Require("host"))
+
+	return component.NewSuite("top").
+		WithMeta(vars.NewMeta().Default("user", "u1")).(component.Group).
+		Run(component.NewRetry(3, 0).Run(producer)).
+		Run(consumer)
+}
+
+func TestDumpFormat_tree(t *testing.T) {
+	buf := new(bytes.Buffer)
+	require.NoError(t, gestalt.DumpFormat(buf, graphComponent(), gestalt.FormatTree))
+
+	assert.Equal(t, ""+
+		"top [defaults: user=u1]\n"+
+		"  producer.retry (pass-through)\n"+
+		"    producer [exports: host]\n"+
+		"  consumer [requires: host]\n",
+		buf.String())
+}
+
+func TestDumpFormat_json(t *testing.T) {
+	buf := new(bytes.Buffer)
+	require.NoError(t, gestalt.DumpFormat(buf, graphComponent(), gestalt.FormatJSON))
+
+	var node struct {
+		Name     string
+		Defaults map[string]string
+		Children []struct {
+			Path        string
+			PassThrough bool
+			Requires    []string
+		}
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &node))
+
+	assert.Equal(t, "top", node.Name)
+	assert.Equal(t, map[string]string{"user": "u1"}, node.Defaults)
+	require.Len(t, node.Children, 2)
+	assert.True(t, node.Children[0].PassThrough)
+	assert.Equal(t, "/top/consumer", node.Children[1].Path)
+	assert.Equal(t, []string{"host"}, node.Children[1].Requires)
+}
+
+func TestDumpFormat_flow(t *testing.T) {
+	buf := new(bytes.Buffer)
+	require.NoError(t, gestalt.DumpFormat(buf, graphComponent(), gestalt.FormatDOT))
+	assert.Contains(t, buf.String(), `n2 -> n3 [label="host"`)
+
+	buf.Reset()
+	require.NoError(t, gestalt.DumpFormat(buf, graphComponent(), gestalt.FormatMermaid))
+	assert.Contains(t, buf.String(), `n1(["producer.retry"])`)
+	assert.Contains(t, buf.String(), `n2 -. "host" .-> n3`)
+}
+
+func TestDumpFormat_unknown(t *testing.T) {
+	assert.Error(t, gestalt.DumpFormat(new(bytes.Buffer), graphComponent(), "xml"))
+}
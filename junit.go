@@ -0,0 +1,385 @@
+package gestalt
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name          `xml:"testsuites"`
+	Name     string            `xml:"name,attr"`
+	Tests    int               `xml:"tests,attr"`
+	Failures int               `xml:"failures,attr"`
+	Skipped  int               `xml:"skipped,attr"`
+	Time     string            `xml:"time,attr"`
+	Suites   []*junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Cases    []*junitTestCase `xml:"testcase"`
+
+	elapsed time.Duration
+	listed  bool
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failures  []*junitError `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+}
+
+type junitSkipped struct{}
+
+type junitError struct {
+	Message string `xml:"message,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// junitVisitor records leaf components as test cases grouped under
+// their nearest enclosing (non pass-through) composite component.
+//
+// The outcome of a case is decided once the pass-through wrappers
+// enclosing it have popped, as they may clear its errors (Ignore,
+// Retry).
+type junitVisitor struct {
+	*junitResults
+
+	current []*junitTestSuite
+	stack   []*junitFrame
+
+	// number of pass-through components on the stack, including
+	// those of the visitor this one was forked from.
+	wrappers int
+
+	// top frame of the visitor this one was forked from.
+	parent *junitFrame
+}
+
+// results shared by a junitVisitor and its forks.
+type junitResults struct {
+	mtx      sync.Mutex
+	name     string
+	suites   []*junitTestSuite
+	paths    map[string]*junitTestSuite
+	cases    map[string]*junitTestCase
+	elapsed  time.Duration
+	reported map[error]bool
+}
+
+type junitFrame struct {
+	start       time.Time
+	passThrough bool
+	popped      bool
+
+	// cases of descendants waiting on enclosing pass-through components.
+	pending []*junitPending
+}
+
+type junitPending struct {
+	suite   *junitTestSuite
+	path    string
+	elapsed time.Duration
+	skipped bool
+	errs    []error
+
+	// failures added to the case rather than replacing its outcome.
+	unreported bool
+}
+
+func newJUnitVisitor(name string) *junitVisitor {
+	return &junitVisitor{
+		junitResults: &junitResults{
+			name:     name,
+			paths:    make(map[string]*junitTestSuite),
+			cases:    make(map[string]*junitTestCase),
+			reported: make(map[error]bool),
+		},
+	}
+}
+
+func (h *junitVisitor) Fork() Visitor {
+	fork := &junitVisitor{
+		junitResults: h.junitResults,
+		current:      append([]*junitTestSuite(nil), h.current...),
+		wrappers:     h.wrappers,
+		parent:       h.parent,
+	}
+	if sz := len(h.stack); sz > 0 {
+		fork.parent = h.stack[sz-1]
+	}
+	return fork
+}
+
+func (h *junitVisitor) Push(t Traverser, node Component) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	h.stack = append(h.stack, &junitFrame{
+		start:       time.Now(),
+		passThrough: node.IsPassThrough(),
+	})
+
+	if node.IsPassThrough() {
+		h.wrappers++
+	}
+
+	if !isSuite(node) {
+		return
+	}
+
+	h.current = append(h.current, h.suiteFor(t.Path()))
+}
+
+func (h *junitVisitor) Pop(t Traverser, node Component) {
+	topidx := len(h.stack) - 1
+	if topidx < 0 {
+		return
+	}
+
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	frame := h.stack[topidx]
+	frame.popped = true
+	h.stack = h.stack[0:topidx]
+
+	if frame.passThrough {
+		h.wrappers--
+	}
+
+	delta := time.Now().Sub(frame.start)
+
+	if topidx == 0 && h.parent == nil {
+		h.elapsed += delta
+	}
+
+	pending := frame.pending
+
+	if isSuite(node) {
+		if sz := len(h.current); sz > 0 {
+			suite := h.current[sz-1]
+			suite.elapsed += delta
+			pending = append(pending, h.unreported(t, suite, delta)...)
+			h.current = h.current[0 : sz-1]
+		}
+	}
+
+	if s, ok := t.(skipper); ok && s.Skipped() {
+		pending = append(pending, h.newPending(t, delta, true))
+	} else if isLeaf(node) {
+		pending = append(pending, h.newPending(t, delta, false))
+	}
+
+	// drop errors cleared by this component.
+	if e, ok := t.(Evaluator); ok {
+		for _, p := range pending {
+			p.errs = retainErrors(p.errs, e.Errors())
+		}
+	}
+
+	// wait for enclosing wrappers, unless they have already popped,
+	// as they do for background components.
+	if h.wrappers > 0 {
+		parent := h.parent
+		if topidx > 0 {
+			parent = h.stack[topidx-1]
+		}
+		if parent != nil && !parent.popped {
+			parent.pending = append(parent.pending, pending...)
+			return
+		}
+	}
+
+	for _, p := range pending {
+		h.addCase(p)
+	}
+}
+
+func (h *junitVisitor) Report(w io.Writer) error {
+	report := &junitTestSuites{Name: h.name, Suites: h.suites}
+
+	for _, suite := range h.suites {
+		suite.Tests = len(suite.Cases)
+		suite.Failures = 0
+		suite.Skipped = 0
+		for _, tc := range suite.Cases {
+			switch {
+			case len(tc.Failures) > 0:
+				suite.Failures++
+			case tc.Skipped != nil:
+				suite.Skipped++
+			}
+		}
+		suite.Time = fmtSeconds(suite.elapsed)
+
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Skipped += suite.Skipped
+	}
+	report.Time = fmtSeconds(h.elapsed)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func (h *junitVisitor) suiteFor(path string) *junitTestSuite {
+	suite, ok := h.paths[path]
+	if !ok {
+		suite = &junitTestSuite{Name: path}
+		h.paths[path] = suite
+	}
+	return suite
+}
+
+func (h *junitVisitor) newPending(t Traverser, delta time.Duration, skipped bool) *junitPending {
+	p := &junitPending{path: t.Path(), elapsed: delta, skipped: skipped}
+
+	if sz := len(h.current); sz > 0 {
+		p.suite = h.current[sz-1]
+	} else {
+		p.suite = h.suiteFor(p.path)
+	}
+
+	if e, ok := t.(Evaluator); ok && !skipped {
+		p.errs = e.Errors()
+		for _, err := range p.errs {
+			h.reported[err] = true
+		}
+	}
+	return p
+}
+
+func (h *junitVisitor) addCase(p *junitPending) {
+	if p.unreported && len(p.errs) == 0 {
+		return
+	}
+
+	tc := h.caseFor(p.suite, p.path)
+
+	switch {
+	case p.unreported:
+		if tc.Time == "" {
+			tc.Time = fmtSeconds(p.elapsed)
+		}
+	default:
+		// retried components are reported once, with their final outcome.
+		tc.Time = fmtSeconds(p.elapsed)
+		tc.Failures = nil
+		tc.Skipped = nil
+		if p.skipped {
+			tc.Skipped = &junitSkipped{}
+		}
+	}
+
+	for _, err := range p.errs {
+		tc.Failures = append(tc.Failures, newJUnitError(err))
+	}
+}
+
+// errors which weren't raised by a leaf component, such as failures
+// of background components, as cases of the suite.  They are timed
+// from the start of the suite.
+func (h *junitVisitor) unreported(t Traverser, suite *junitTestSuite, delta time.Duration) []*junitPending {
+	e, ok := t.(Evaluator)
+	if !ok {
+		return nil
+	}
+
+	var pending []*junitPending
+	for _, err := range e.Errors() {
+		if h.reported[err] {
+			continue
+		}
+		h.reported[err] = true
+
+		path := t.Path()
+		if errp, ok := err.(Error); ok {
+			path = errp.Path()
+		}
+
+		pending = append(pending, &junitPending{
+			suite:      suite,
+			path:       path,
+			elapsed:    delta,
+			errs:       []error{err},
+			unreported: true,
+		})
+	}
+	return pending
+}
+
+func (h *junitVisitor) caseFor(suite *junitTestSuite, path string) *junitTestCase {
+	if !suite.listed {
+		suite.listed = true
+		h.suites = append(h.suites, suite)
+	}
+
+	tc, ok := h.cases[path]
+	if !ok {
+		tc = &junitTestCase{Name: path, Classname: suite.Name}
+		h.cases[path] = tc
+		suite.Cases = append(suite.Cases, tc)
+	}
+	return tc
+}
+
+func newJUnitError(err error) *junitError {
+	failure := &junitError{Message: err.Error()}
+	if errd, ok := err.(ErrorWithDetail); ok {
+		failure.Body = errd.Detail()
+	}
+	return failure
+}
+
+// errors of errs which are in current.
+func retainErrors(errs []error, current []error) []error {
+	return retainErrorsBy(errs, current, func(err error) error { return err })
+}
+
+// errors of errs which are in current, compared by key.
+func retainErrorsBy(errs []error, current []error, key func(error) error) []error {
+	var result []error
+	for _, err := range errs {
+		for _, cur := range current {
+			if key(err) == key(cur) {
+				result = append(result, err)
+				break
+			}
+		}
+	}
+	return result
+}
+
+func isSuite(node Component) bool {
+	_, ok := node.(CompositeComponent)
+	return ok && !node.IsPassThrough()
+}
+
+func isLeaf(node Component) bool {
+	_, ok := node.(CompositeComponent)
+	return !ok && !node.IsPassThrough()
+}
+
+func fmtSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
@@ -0,0 +1,141 @@
+package gestalt_test
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/ovrclk/gestalt"
+	"github.com/ovrclk/gestalt/component"
+	"github.com/ovrclk/gestalt/exec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type junitReport struct {
+	Tests    int `xml:"tests,attr"`
+	Failures int `xml:"failures,attr"`
+	Suites   []struct {
+		Name     string `xml:"name,attr"`
+		Tests    int    `xml:"tests,attr"`
+		Failures int    `xml:"failures,attr"`
+		Cases    []struct {
+			Name     string `xml:"name,attr"`
+			Time     string `xml:"time,attr"`
+			Failures []struct {
+				Message string `xml:"message,attr"`
+				Body    string `xml:",chardata"`
+			} `xml:"failure"`
+		} `xml:"testcase"`
+	} `xml:"testsuite"`
+}
+
+func runJUnit(t *testing.T, cmp gestalt.Component, args ...string) (junitReport, int) {
+	f, err := ioutil.TempFile("", "gestalt-junit")
+	require.NoError(t, err)
+	f.Close()
+	defer os.Remove(f.Name())
+
+	status := 0
+	gestalt.NewRunner().
+		WithComponent(cmp).
+		WithArgs(append([]string{"eval", "--junit", f.Name()}, args...)).
+		WithTerminate(func(s int) { status = s }).
+		Run()
+
+	buf, err := ioutil.ReadFile(f.Name())
+	require.NoError(t, err)
+
+	report := junitReport{}
+	require.NoError(t, xml.Unmarshal(buf, &report))
+
+	for _, suite := range report.Suites {
+		for _, tc := range suite.Cases {
+			_, err := strconv.ParseFloat(tc.Time, 64)
+			assert.NoError(t, err, "time of %v", tc.Name)
+		}
+	}
+
+	return report, status
+}
+
+func TestJUnit(t *testing.T) {
+	cmp := component.NewSuite("top").
+		Run(gestalt.NoopComponent("a")).
+		Run(component.NewGroup("b").
+			Run(component.NewRetry(2, 0).
+				Run(gestalt.NoopComponent("c"))).
+			Run(exec.SH("d", "echo", "hello; false")))
+
+	report, status := runJUnit(t, cmp)
+	assert.NotEqual(t, 0, status)
+
+	assert.Equal(t, 3, report.Tests)
+	assert.Equal(t, 1, report.Failures)
+
+	require.Len(t, report.Suites, 2)
+
+	assert.Equal(t, "/top", report.Suites[0].Name)
+	require.Len(t, report.Suites[0].Cases, 1)
+	assert.Equal(t, "/top/a", report.Suites[0].Cases[0].Name)
+	assert.Empty(t, report.Suites[0].Cases[0].Failures)
+
+	suite := report.Suites[1]
+	assert.Equal(t, "/top/b", suite.Name)
+	assert.Equal(t, 2, suite.Tests)
+	assert.Equal(t, 1, suite.Failures)
+	require.Len(t, suite.Cases, 2)
+	assert.Equal(t, "/top/b/c", suite.Cases[0].Name)
+	assert.Equal(t, "/top/b/d", suite.Cases[1].Name)
+
+	require.Len(t, suite.Cases[1].Failures, 1)
+	failure := suite.Cases[1].Failures[0]
+	assert.Contains(t, failure.Message, "/top/b/d")
+	assert.Contains(t, failure.Body, "hello\n")
+}
+
+func TestJUnit_background(t *testing.T) {
+	cmp := component.NewSuite("top").
+		Run(component.NewParallel("p").
+			Run(gestalt.NoopComponent("a")).
+			Run(exec.SH("b", "false")))
+
+	report, status := runJUnit(t, cmp)
+	assert.NotEqual(t, 0, status)
+	assert.Equal(t, 2, report.Tests)
+	assert.Equal(t, 1, report.Failures)
+
+	require.Len(t, report.Suites, 1)
+	suite := report.Suites[0]
+	assert.Equal(t, "/top/p", suite.Name)
+
+	cases := make(map[string]int)
+	for _, tc := range suite.Cases {
+		cases[tc.Name] = len(tc.Failures)
+	}
+	assert.Equal(t, map[string]int{"/top/p/a": 0, "/top/p/b": 1}, cases)
+}
+
+func TestJUnit_clearedErrors(t *testing.T) {
+	attempts := 0
+	flaky := gestalt.NewComponent("flaky", func(_ gestalt.Evaluator) error {
+		if attempts++; attempts < 2 {
+			return fmt.Errorf("flaky")
+		}
+		return nil
+	})
+
+	cmp := component.NewSuite("top").
+		Run(component.NewIgnore().Run(exec.SH("ignored", "false"))).
+		Run(component.NewRetry(2, 0).Run(flaky)).
+		Run(component.NewIgnore().Run(component.NewGroup("g").
+			Run(exec.SH("nested", "false"))))
+
+	report, status := runJUnit(t, cmp)
+	assert.Equal(t, 0, status)
+	assert.Equal(t, 0, report.Failures)
+	assert.Equal(t, 3, report.Tests)
+}
@@ -22,11 +22,18 @@ type Logger interface {
 	Stop(error)
 }
 
+// OutputStreamer is implemented by loggers which can show the output
+// of commands as it is produced.
+type OutputStreamer interface {
+	StreamOutput() bool
+}
+
 type logger struct {
 	path   string
 	log    logrus.FieldLogger
 	out    io.Writer
 	logOut io.Writer
+	stream bool
 }
 
 func (l *logger) Log() logrus.FieldLogger {
@@ -42,11 +49,11 @@ func (l *logger) Message(msg string, args ...interface{}) {
 }
 
 func (l *logger) Dump(msg string) {
+	prefix := color.New(color.FgWhite, color.Bold).Sprintf("%v: ", l.path)
 	scanner := bufio.NewScanner(bytes.NewBuffer([]byte(msg)))
 	for scanner.Scan() {
-		color.New(color.FgWhite, color.Bold).Fprintf(l.logOut, "%v: ", l.path)
-		l.logOut.Write(scanner.Bytes())
-		l.logOut.Write([]byte("\n"))
+		// write each line at once so that concurrent streams don't interleave.
+		l.logOut.Write([]byte(prefix + scanner.Text() + "\n"))
 	}
 }
 
@@ -58,16 +65,21 @@ func (l *logger) Stop(err error) {
 	}
 }
 
+func (l *logger) StreamOutput() bool {
+	return l.stream
+}
+
 func (l *logger) CloneFor(path string) Logger {
-	return &logger{path, l.log.WithField("path", path), l.out, l.logOut}
+	return &logger{path, l.log.WithField("path", path), l.out, l.logOut, l.stream}
 }
 
 func (l *logger) Clone() Logger {
-	return &logger{l.path, l.log, l.out, l.logOut}
+	return &logger{l.path, l.log, l.out, l.logOut, l.stream}
 }
 
 type logBuilder struct {
-	log *logrus.Logger
+	log    *logrus.Logger
+	stream bool
 }
 
 func newLogBuilder() *logBuilder {
@@ -88,6 +100,11 @@ func (lb *logBuilder) WithLevel(level string) *logBuilder {
 	return lb
 }
 
+func (lb *logBuilder) WithStreamOutput(stream bool) *logBuilder {
+	lb.stream = stream
+	return lb
+}
+
 func (lb *logBuilder) Logger() Logger {
-	return &logger{"", lb.log, os.Stdout, lb.log.Out}
+	return &logger{"", lb.log, os.Stdout, lb.log.Out, lb.stream}
 }
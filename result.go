@@ -0,0 +1,72 @@
+package gestalt
+
+import (
+	"time"
+
+	"github.com/ovrclk/gestalt/vars"
+)
+
+type Status string
+
+const (
+	StatusPass Status = "pass"
+	StatusFail Status = "fail"
+	StatusSkip Status = "skip"
+)
+
+type PathResult struct {
+	Path    string
+	Status  Status
+	Count   int
+	Total   time.Duration
+	Average time.Duration
+
+	// resources used by processes run directly by the component,
+	// summed over all evaluations.  nil if there were none.
+	Usage *Usage
+}
+
+type RunResult struct {
+	// results for each path, in order of first evaluation.
+	Paths  []PathResult
+	Errors []error
+	Vars   vars.Vars
+}
+
+func newRunResult(profiler *profileVisitor, e Evaluator) *RunResult {
+	result := &RunResult{
+		Paths:  make([]PathResult, 0, len(profiler.profiles)),
+		Errors: e.Errors(),
+		Vars:   e.Vars().Clone(),
+	}
+	for _, p := range profiler.profiles {
+		result.Paths = append(result.Paths, PathResult{
+			Path:    p.path,
+			Status:  p.status,
+			Count:   p.count,
+			Total:   p.total,
+			Average: p.avg,
+			Usage:   p.usage,
+		})
+	}
+	return result
+}
+
+func (r *RunResult) Passed() bool {
+	return len(r.Errors) == 0
+}
+
+// status of the component currently being evaluated.
+func statusOf(t Traverser) Status {
+	e, ok := t.(Evaluator)
+	switch {
+	case !ok:
+		return StatusPass
+	case len(e.Errors()) > 0:
+		return StatusFail
+	}
+	if s, ok := t.(skipper); ok && s.Skipped() {
+		return StatusSkip
+	}
+	return StatusPass
+}
@@ -1,11 +1,17 @@
 package gestalt
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
+	"github.com/ovrclk/gestalt/cassette"
 	"github.com/ovrclk/gestalt/vars"
 
 	kingpin "gopkg.in/alecthomas/kingpin.v2"
@@ -27,6 +33,7 @@ type Runner interface {
 	WithComponent(Component) Runner
 	WithTerminate(func(status int)) Runner
 	Run()
+	Execute() (*RunResult, error)
 }
 
 type runner struct {
@@ -64,14 +71,62 @@ func (r *runner) Run() {
 		return
 	}
 
-	switch kingpin.MustParse(opts.app.Parse(r.args)) {
+	result, err := r.execute(opts, kingpin.MustParse(opts.app.Parse(r.args)))
+
+	if result != nil {
+		r.showResult(result)
+	}
+
+	if err != nil {
+		opts.app.FatalIfError(err, "")
+		return
+	}
+
+	if result != nil && !result.Passed() {
+		opts.app.Fatalf("eval failed")
+	}
+}
+
+// Execute runs the command given by the runner's arguments and returns
+// the result of evaluation, if any, rather than printing it and
+// terminating.  Help and usage output is returned as an error.  The
+// show command, and messages and logs of the evaluation, are still
+// written to their usual outputs.
+func (r *runner) Execute() (*RunResult, error) {
+	opts := newOptions(r)
+
+	if r.cmp == nil {
+		return nil, fmt.Errorf("no component given")
+	}
+
+	usage := new(bytes.Buffer)
+	terminated := false
+	opts.app.
+		UsageWriter(usage).
+		ErrorWriter(usage).
+		Terminate(func(int) { terminated = true })
+
+	cmd, err := opts.app.Parse(r.args)
+	if err != nil {
+		return nil, err
+	}
+	if terminated {
+		return nil, fmt.Errorf("%v", strings.TrimSpace(usage.String()))
+	}
+
+	return r.execute(opts, cmd)
+}
+
+func (r *runner) execute(opts *options, cmd string) (*RunResult, error) {
+	switch cmd {
 	case opts.cmdShow.FullCommand():
-		r.doShow(opts)
+		return nil, r.doShow(opts)
 	case opts.cmdEval.FullCommand():
-		r.doEval(opts)
+		return r.doEval(opts)
 	case opts.cmdValidate.FullCommand():
-		r.doValidate(opts)
+		return nil, r.doValidate(opts)
 	}
+	return nil, nil
 }
 
 type options struct {
@@ -82,10 +137,30 @@ type options struct {
 
 	vars *map[string]string
 
+	varsFiles    *[]string
+	envPrefix    *string
+	profilesFile *string
+	profile      *string
+
 	cmdShow *kingpin.CmdClause
+	format  *string
 
 	cmdEval *kingpin.CmdClause
 	trace   *bool
+	junit   **os.File
+	events  *string
+
+	only *[]string
+	skip *[]string
+
+	timeout *time.Duration
+
+	record *string
+	replay *string
+
+	bgFailFast *bool
+
+	streamOutput *bool
 
 	breakpoints *[]string
 	failpoints  *[]string
@@ -93,12 +168,51 @@ type options struct {
 	cmdValidate *kingpin.CmdClause
 }
 
-func (opts *options) getVars() vars.Vars {
+// variables from, in increasing precedence: the selected profile,
+// vars files, environment, and --set.
+func (opts *options) getVars() (vars.Vars, error) {
 	v := vars.NewVars()
+
+	if *opts.profile != "" {
+		if *opts.profilesFile == "" {
+			return nil, fmt.Errorf("profile %v given without --profiles-file", *opts.profile)
+		}
+		pv, err := vars.LoadProfile(*opts.profilesFile, *opts.profile)
+		if err != nil {
+			return nil, err
+		}
+		v = v.Merge(pv)
+	}
+
+	for _, path := range *opts.varsFiles {
+		fv, err := vars.LoadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		v = v.Merge(fv)
+	}
+
+	if *opts.envPrefix != "" {
+		v = v.Merge(vars.FromEnv(*opts.envPrefix, os.Environ()))
+	}
+
 	if opts.vars != nil {
 		v = v.Merge(vars.FromMap(*opts.vars))
 	}
-	return v
+	return v, nil
+}
+
+// cassette for --record or --replay, if given.
+func (opts *options) getCassette() (*cassette.Cassette, error) {
+	switch {
+	case *opts.record != "" && *opts.replay != "":
+		return nil, fmt.Errorf("--record and --replay are mutually exclusive")
+	case *opts.record != "":
+		return cassette.New(), nil
+	case *opts.replay != "":
+		return cassette.Load(*opts.replay)
+	}
+	return nil, nil
 }
 
 func newOptions(r *runner) *options {
@@ -121,6 +235,22 @@ func newOptions(r *runner) *options {
 	opts.vars = opts.app.
 		Flag("set", "set variables").Short('s').StringMap()
 
+	opts.varsFiles = opts.app.
+		Flag("vars-file", "load variables from YAML, JSON, or dotenv file").
+		Strings()
+
+	opts.envPrefix = opts.app.
+		Flag("env-prefix", "import environment variables with prefix (GESTALT_GROUP_NAME => group-name)").
+		String()
+
+	opts.profilesFile = opts.app.
+		Flag("profiles-file", "YAML or JSON file of variable profiles").
+		String()
+
+	opts.profile = opts.app.
+		Flag("profile", "load variables from named profile").
+		String()
+
 	opts.cmdEval = opts.app.
 		Command("eval", "run components")
 
@@ -128,6 +258,44 @@ func newOptions(r *runner) *options {
 		Flag("trace", "Trace execution").
 		Bool()
 
+	opts.junit = opts.cmdEval.
+		Flag("junit", "Write JUnit XML report to file").
+		OpenFile(os.O_TRUNC|os.O_WRONLY|os.O_CREATE, 0666)
+
+	opts.events = opts.cmdEval.
+		Flag("events", "Write JSON event stream to file ('-' for stdout)").
+		String()
+
+	opts.only = opts.cmdEval.
+		Flag("only", "only run components matching pattern").
+		Strings()
+
+	opts.skip = opts.cmdEval.
+		Flag("skip", "skip components matching pattern").
+		Strings()
+
+	opts.record = opts.cmdEval.
+		Flag("record", "record command invocations to file").
+		PlaceHolder("CASSETTE").
+		String()
+
+	opts.replay = opts.cmdEval.
+		Flag("replay", "replay command invocations from file instead of running them").
+		PlaceHolder("CASSETTE").
+		String()
+
+	opts.timeout = opts.cmdEval.
+		Flag("timeout", "fail if evaluation takes longer than duration").
+		Duration()
+
+	opts.bgFailFast = opts.cmdEval.
+		Flag("bg-fail-fast", "stop enclosing group as soon as a background component fails").
+		Bool()
+
+	opts.streamOutput = opts.cmdEval.
+		Flag("stream-output", "log command output as it is produced").
+		Bool()
+
 	opts.breakpoints = opts.app.
 		Flag("breakpoint", "add breakpoint").
 		Short('B').
@@ -141,19 +309,26 @@ func newOptions(r *runner) *options {
 	opts.cmdShow = opts.app.
 		Command("show", "display component tree")
 
+	opts.format = opts.cmdShow.
+		Flag("format", "output format ("+strings.Join(graphFormats, "|")+")").
+		Default(FormatPaths).
+		Enum(graphFormats...)
+
 	opts.cmdValidate = opts.app.
 		Command("validate", "validate vars")
 
 	return opts
 }
 
-func (r *runner) doEval(opts *options) {
+func (r *runner) doEval(opts *options) (*RunResult, error) {
 
 	donech := make(chan interface{})
+	defer close(donech)
 
 	lb := newLogBuilder().
 		WithLevel(*opts.logLevel).
-		WithLogOut(*opts.logFile)
+		WithLogOut(*opts.logFile).
+		WithStreamOutput(*opts.streamOutput)
 
 	profiler := newProfileVisitor()
 
@@ -163,7 +338,25 @@ func (r *runner) doEval(opts *options) {
 		visitors = append(visitors, newTraceVisitor(os.Stdout))
 	}
 
+	var junit *junitVisitor
+	if *opts.junit != nil {
+		junit = newJUnitVisitor(r.cmp.Name())
+		visitors = append(visitors, junit)
+	}
+
+	var events io.WriteCloser
+	if *opts.events != "" {
+		out, err := openOutput(*opts.events)
+		if err != nil {
+			return nil, fmt.Errorf("events: %v", err)
+		}
+		defer out.Close()
+		events = out
+		visitors = append(visitors, newEventVisitor(events))
+	}
+
 	e := NewEvaluatorWithLogger(lb.Logger(), visitors...)
+	e.failFast = *opts.bgFailFast
 
 	if opts.breakpoints != nil || opts.failpoints != nil {
 		handler := r.createDebugger(donech)
@@ -182,30 +375,83 @@ func (r *runner) doEval(opts *options) {
 		e.handler = handler
 	}
 
-	e.Vars().Merge(opts.getVars())
+	if len(*opts.only) > 0 || len(*opts.skip) > 0 {
+		e.handler = newFocusHandler(*opts.only, *opts.skip, e.handler)
+	}
 
-	if err := r.showUnresolvedVars(opts, e.Vars()); err != nil {
-		opts.app.FatalIfError(err, "")
+	v, err := opts.getVars()
+	if err != nil {
+		return nil, err
+	}
+	e.Vars().Merge(v)
+
+	if err := r.checkUnresolvedVars(e.Vars()); err != nil {
+		return nil, err
+	}
+
+	cas, err := opts.getCassette()
+	if err != nil {
+		return nil, err
+	}
+	if cas != nil {
+		e.ctx.Derive(func(ctx context.Context) context.Context {
+			return cassette.NewContext(ctx, cas)
+		})
+	}
+
+	if *opts.timeout > 0 {
+		e.ctx.SetTimeout(*opts.timeout)
 	}
 
 	e.Evaluate(r.cmp)
 	e.Wait()
-	close(donech)
 
+	if *opts.record != "" {
+		if err := cas.Save(*opts.record); err != nil {
+			return nil, fmt.Errorf("record: %v", err)
+		}
+	}
+
+	if e.Context().Err() == context.DeadlineExceeded {
+		err := fmt.Errorf("timed out after %v", *opts.timeout)
+		e.err.Add(NewError("/"+r.cmp.Name(), err))
+	}
+
+	result := newRunResult(profiler, e)
+
+	if junit != nil {
+		err := junit.Report(*opts.junit)
+		(*opts.junit).Close()
+		if err != nil {
+			return result, fmt.Errorf("junit: %v", err)
+		}
+	}
+
+	return result, nil
+}
+
+func (r *runner) showResult(result *RunResult) {
 	// show profile info
 	fmt.Printf("\nprofile info:\n\n")
-	for _, p := range profiler.profiles {
-		fmt.Printf("%-5v%-10v%v\n", p.count, fmtDuration(p.avg), p.path)
+	for _, p := range result.Paths {
+		user, sys, rss := "-", "-", "-"
+		if p.Usage != nil {
+			user = fmtDuration(p.Usage.UserCPU)
+			sys = fmtDuration(p.Usage.SystemCPU)
+			rss = fmtBytes(p.Usage.MaxRSS)
+		}
+		fmt.Printf("%-5v%-10v%-10v%-10v%-10v%v\n",
+			p.Count, fmtDuration(p.Average), user, sys, rss, p.Path)
 	}
 
-	if !e.HasError() {
+	if result.Passed() {
 		fmt.Printf("\nall tests passed\n")
 		return
 	}
 
 	fprintErr(os.Stderr, "\n\nEvaluation of %v failed:\n\n", r.cmp.Name())
 
-	for _, err := range e.Errors() {
+	for _, err := range result.Errors {
 		if err, ok := err.(Error); ok {
 
 			fmt.Fprintf(os.Stderr, "%v\n", err)
@@ -214,19 +460,21 @@ func (r *runner) doEval(opts *options) {
 			fmt.Fprintf(os.Stderr, "Unknown Error:\n%v\n", err)
 		}
 	}
-	opts.app.Fatalf("eval failed")
 }
 
-func (r *runner) doShow(opts *options) {
-	Dump(r.cmp)
+func (r *runner) doShow(opts *options) error {
+	return DumpFormat(os.Stdout, r.cmp, *opts.format)
 }
 
-func (r *runner) doValidate(opts *options) {
-	err := r.showUnresolvedVars(opts, opts.getVars())
-	opts.app.FatalIfError(err, "")
+func (r *runner) doValidate(opts *options) error {
+	v, err := opts.getVars()
+	if err != nil {
+		return err
+	}
+	return r.checkUnresolvedVars(v)
 }
 
-func (r *runner) showUnresolvedVars(opts *options, vars vars.Vars) error {
+func (r *runner) checkUnresolvedVars(vars vars.Vars) error {
 
 	unresolved := ValidateWith(r.cmp, vars)
 
@@ -234,12 +482,12 @@ func (r *runner) showUnresolvedVars(opts *options, vars vars.Vars) error {
 		return nil
 	}
 
+	var missing []string
 	for _, x := range unresolved {
-		opts.app.Errorf("Missing variables:\n")
-		opts.app.Errorf("%v.%v", x.Path, x.Name)
+		missing = append(missing, fmt.Sprintf("%v.%v", x.Path, x.Name))
 	}
 
-	return fmt.Errorf("missing variables")
+	return fmt.Errorf("missing variables: %v", strings.Join(missing, ", "))
 }
 
 func (r *runner) createDebugger(donech <-chan interface{}) *debugHandler {
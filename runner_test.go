@@ -0,0 +1,141 @@
+package gestalt_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ovrclk/gestalt"
+	"github.com/ovrclk/gestalt/component"
+	"github.com/ovrclk/gestalt/vars"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecute(t *testing.T) {
+	cmp := component.NewSuite("top").
+		Run(exportComponent("a", "foo")).
+		Run(gestalt.NoopComponent("skipped")).
+		Run(gestalt.NewComponent("fail", func(_ gestalt.Evaluator) error {
+			return fmt.Errorf("failed")
+		})).
+		WithMeta(vars.NewMeta().Export("a"))
+
+	terminated := false
+	result, err := gestalt.NewRunner().
+		WithComponent(cmp).
+		WithArgs([]string{"eval", "--skip", "skipped"}).
+		WithTerminate(func(int) { terminated = true }).
+		Execute()
+
+	require.NoError(t, err)
+	require.NotNil(t, result)
+	assert.False(t, terminated)
+
+	assert.False(t, result.Passed())
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, "/top/fail: failed", result.Errors[0].Error())
+
+	assert.Equal(t, "foo", result.Vars.Get("a"))
+
+	statuses := make(map[string]gestalt.Status)
+	for _, p := range result.Paths {
+		assert.Equal(t, 1, p.Count)
+		statuses[p.Path] = p.Status
+	}
+	assert.Equal(t, map[string]gestalt.Status{
+		"/top":         gestalt.StatusFail,
+		"/top/create":  gestalt.StatusPass,
+		"/top/skipped": gestalt.StatusSkip,
+		"/top/fail":    gestalt.StatusFail,
+	}, statuses)
+}
+
+func TestExecute_invalidArgs(t *testing.T) {
+	result, err := gestalt.NewRunner().
+		WithComponent(gestalt.NoopComponent("top")).
+		WithArgs([]string{"eval", "--bogus"}).
+		Execute()
+
+	assert.Error(t, err)
+	assert.Nil(t, result)
+}
+
+func TestExecute_parallel(t *testing.T) {
+	ran := make(chan string, 3)
+	tracer := func(name string) gestalt.Component {
+		return gestalt.NewComponent(name, func(_ gestalt.Evaluator) error {
+			ran <- name
+			return nil
+		})
+	}
+
+	cmp := component.NewSuite("top").
+		Run(component.NewParallel("p").
+			MaxConcurrency(1).
+			Run(tracer("a")).
+			Run(tracer("b")).
+			Run(tracer("c")))
+
+	result, err := gestalt.NewRunner().
+		WithComponent(cmp).
+		WithArgs([]string{"eval", "--skip", "p/b"}).
+		Execute()
+	require.NoError(t, err)
+	close(ran)
+
+	names := make([]string, 0)
+	for name := range ran {
+		names = append(names, name)
+	}
+	assert.ElementsMatch(t, []string{"a", "c"}, names)
+
+	statuses := make(map[string]gestalt.Status)
+	for _, p := range result.Paths {
+		statuses[p.Path] = p.Status
+	}
+	assert.Equal(t, gestalt.StatusPass, statuses["/top/p/a"])
+	assert.Equal(t, gestalt.StatusSkip, statuses["/top/p/b"])
+	assert.Equal(t, gestalt.StatusPass, statuses["/top/p/c"])
+
+	result, err = gestalt.NewRunner().
+		WithComponent(component.NewSuite("top").
+			Run(component.NewParallel("p").
+				Run(gestalt.NoopComponent("a")).
+				Run(gestalt.NoopComponent("b")))).
+		WithArgs([]string{"eval", "--only", "p/b"}).
+		Execute()
+	require.NoError(t, err)
+
+	statuses = make(map[string]gestalt.Status)
+	for _, p := range result.Paths {
+		statuses[p.Path] = p.Status
+	}
+	assert.Equal(t, gestalt.StatusSkip, statuses["/top/p/a.parallel"])
+	assert.NotContains(t, statuses, "/top/p/a")
+	assert.Equal(t, gestalt.StatusPass, statuses["/top/p/b"])
+}
+
+func TestExecute_errors(t *testing.T) {
+	cmp := gestalt.NewComponent("check", func(_ gestalt.Evaluator) error {
+		return nil
+	}).WithMeta(vars.NewMeta().Require("a"))
+
+	terminated := false
+	result, err := gestalt.NewRunner().
+		WithComponent(cmp).
+		WithArgs([]string{"eval", "--help"}).
+		WithTerminate(func(int) { terminated = true }).
+		Execute()
+	assert.Nil(t, result)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "usage:")
+	}
+	assert.False(t, terminated)
+
+	result, err = gestalt.NewRunner().
+		WithComponent(cmp).
+		WithArgs([]string{"eval"}).
+		Execute()
+	assert.Nil(t, result)
+	assert.EqualError(t, err, "missing variables: /check.a")
+}
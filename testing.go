@@ -0,0 +1,332 @@
+package gestalt
+
+import (
+	"context"
+	"flag"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// grace period given to running commands before the test binary's
+// own -timeout panic.
+const testTimeoutGrace = time.Second
+
+// RunT evaluates c as part of a go test.  Every non pass-through
+// component, including those run in the background or in parallel, is
+// run as a subtest of t, so -run filters apply to component paths.
+func RunT(t *testing.T, c Component) {
+	h := newTestHandler(t)
+
+	e := NewEvaluator()
+	e.handler = h
+
+	if timeout, ok := testTimeout(t); ok {
+		e.ctx.SetTimeout(timeout)
+	}
+
+	interrupted := cancelOnSignal(e.ctx.CurrentCancel())
+
+	e.Evaluate(c)
+	e.Wait()
+
+	// let subtests report before the errors they didn't.
+	h.current().subtests.Wait()
+	h.reportErrors(t, e)
+
+	if e.Context().Err() == context.DeadlineExceeded {
+		t.Errorf("/%v: timed out", c.Name())
+	}
+	if sig := interrupted(); sig != nil {
+		t.Errorf("/%v: interrupted by %v", c.Name(), sig)
+	}
+}
+
+type testHandler struct {
+	*testResults
+
+	stack  []*testCase
+	frames []*testFrame
+	next   evalHandler
+
+	// number of pass-through frames, including those of the handler
+	// this one was forked from.
+	wrappers int
+
+	// top frame of the handler this one was forked from.
+	parent *testFrame
+
+	// subtest the fork was started from; released once the fork's
+	// component has been evaluated.
+	forked *testCase
+}
+
+// state shared by a testHandler and its forks.
+type testResults struct {
+	mtx      sync.Mutex
+	reported map[error]bool
+}
+
+// a component being evaluated.  The subtests of the descendants of a
+// pass-through component are held open until it returns, as it may
+// clear their errors (Ignore, Retry).
+type testFrame struct {
+	passThrough bool
+	popped      bool
+	pending     []*testCase
+}
+
+// a subtest whose outcome has yet to be reported.  It reports once
+// its own subtests, including those of forks started within it, have
+// returned.
+type testCase struct {
+	t        *testing.T
+	errs     []error
+	outcome  chan []error
+	subtests sync.WaitGroup
+}
+
+func newTestHandler(t *testing.T) *testHandler {
+	return &testHandler{
+		testResults: &testResults{reported: make(map[error]bool)},
+		stack:       []*testCase{&testCase{t: t}},
+		next:        defaultEvalHandler,
+	}
+}
+
+// forks run their subtests within the current one.
+func (h *testHandler) fork() evalHandler {
+	current := h.current()
+	current.subtests.Add(1)
+
+	fork := &testHandler{
+		testResults: h.testResults,
+		stack:       []*testCase{current},
+		next:        forkEvalHandler(h.next),
+		wrappers:    h.wrappers,
+		parent:      h.parent,
+		forked:      current,
+	}
+	if sz := len(h.frames); sz > 0 {
+		fork.parent = h.frames[sz-1]
+	}
+	return fork
+}
+
+func (h *testHandler) Eval(e Evaluator, node Component) error {
+	if h.forked != nil && len(h.frames) == 0 {
+		defer h.forked.subtests.Done()
+	}
+
+	if node.IsPassThrough() {
+		h.push(true)
+		result := h.next.Eval(e, node)
+		h.pop(e)
+		return result
+	}
+
+	tc := h.start(node.Name())
+	if tc == nil {
+		if s, ok := e.(skipper); ok {
+			s.Skip()
+		}
+		return nil
+	}
+
+	h.stack = append(h.stack, tc)
+	h.push(false)
+
+	result := h.next.Eval(e, node)
+
+	if result != nil {
+		tc.errs = append(tc.errs, NewError(e.Path(), result))
+	}
+	tc.errs = append(tc.errs, e.Errors()...)
+
+	h.pop(e, tc)
+	h.stack = h.stack[0 : len(h.stack)-1]
+
+	return result
+}
+
+func (h *testHandler) push(passThrough bool) {
+	h.frames = append(h.frames, &testFrame{passThrough: passThrough})
+	if passThrough {
+		h.wrappers++
+	}
+}
+
+// pop the current frame, along with the subtests waiting on it, and
+// report their outcome unless they must wait on an enclosing wrapper.
+func (h *testHandler) pop(e Evaluator, cases ...*testCase) {
+	h.mtx.Lock()
+
+	top := h.frames[len(h.frames)-1]
+	top.popped = true
+	h.frames = h.frames[0 : len(h.frames)-1]
+	if top.passThrough {
+		h.wrappers--
+	}
+
+	// drop errors of descendants cleared by the component.
+	for _, tc := range top.pending {
+		tc.errs = retainErrorsBy(tc.errs, e.Errors(), testErrorKey)
+	}
+	pending := append(top.pending, cases...)
+
+	// wait for enclosing wrappers, unless they have already popped,
+	// as they do for background components.
+	if h.wrappers > 0 {
+		parent := h.parent
+		if sz := len(h.frames); sz > 0 {
+			parent = h.frames[sz-1]
+		}
+		if parent != nil && !parent.popped {
+			parent.pending = append(parent.pending, pending...)
+			h.mtx.Unlock()
+			return
+		}
+	}
+
+	h.mtx.Unlock()
+
+	// descendants are given their outcome before their ancestors.
+	for _, tc := range pending {
+		tc.outcome <- tc.errs
+	}
+}
+
+// start a subtest of the current test.  Returns nil if it was
+// filtered out with -run.
+func (h *testHandler) start(name string) *testCase {
+	tc := &testCase{outcome: make(chan []error)}
+	started := make(chan *testing.T)
+	done := make(chan struct{})
+	parent := h.current()
+
+	parent.subtests.Add(1)
+	go func() {
+		defer parent.subtests.Done()
+		defer close(done)
+		parent.t.Run(name, func(t *testing.T) {
+			started <- t
+			errs := <-tc.outcome
+			// descendants report their errors first.
+			tc.subtests.Wait()
+			for _, err := range errs {
+				h.report(t, err)
+			}
+		})
+	}()
+
+	select {
+	case tc.t = <-started:
+		return tc
+	case <-done:
+		return nil
+	}
+}
+
+func (h *testHandler) current() *testCase {
+	return h.stack[len(h.stack)-1]
+}
+
+// report errors which haven't been reported by a subtest,
+// such as failures of background components.
+func (h *testHandler) reportErrors(t *testing.T, e Evaluator) {
+	t.Helper()
+	for _, err := range e.Errors() {
+		h.report(t, err)
+	}
+}
+
+func (h *testHandler) report(t *testing.T, err error) {
+	t.Helper()
+
+	key := testErrorKey(err)
+	h.mtx.Lock()
+	reported := h.reported[key]
+	h.reported[key] = true
+	h.mtx.Unlock()
+	if reported {
+		return
+	}
+
+	if errd, ok := err.(ErrorWithDetail); ok && errd.Detail() != "" {
+		t.Errorf("%v\n%v", err, errd.Detail())
+	} else {
+		t.Error(err)
+	}
+}
+
+// errors are identified by the error they wrap, as the handler sees
+// component errors before the evaluator wraps them.
+func testErrorKey(err error) error {
+	if errw, ok := err.(*evalError); ok {
+		return errw.Wrapped()
+	}
+	return err
+}
+
+// cancel the evaluation once the test binary receives SIGINT or
+// SIGTERM, so that running commands are terminated rather than left
+// behind.  Further signals have their default effect.  The returned
+// function stops handling signals and returns the one received, if any.
+func cancelOnSignal(cancel context.CancelFunc) func() os.Signal {
+	sigch := make(chan os.Signal, 1)
+	signal.Notify(sigch, syscall.SIGINT, syscall.SIGTERM)
+
+	donech := make(chan struct{})
+	resultch := make(chan os.Signal, 1)
+
+	go func() {
+		select {
+		case sig := <-sigch:
+			signal.Stop(sigch)
+			resultch <- sig
+			cancel()
+		case <-donech:
+			resultch <- nil
+		}
+	}()
+
+	return func() os.Signal {
+		signal.Stop(sigch)
+		close(donech)
+		return <-resultch
+	}
+}
+
+// remaining time before the test binary times out.
+func testTimeout(t *testing.T) (time.Duration, bool) {
+	var deadline time.Time
+
+	if td, ok := interface{}(t).(interface{ Deadline() (time.Time, bool) }); ok {
+		d, ok := td.Deadline()
+		if !ok {
+			return 0, false
+		}
+		deadline = d
+	} else if f := flag.Lookup("test.timeout"); f != nil {
+		getter, ok := f.Value.(flag.Getter)
+		if !ok {
+			return 0, false
+		}
+		timeout, ok := getter.Get().(time.Duration)
+		if !ok || timeout <= 0 {
+			return 0, false
+		}
+		deadline = time.Now().Add(timeout)
+	} else {
+		return 0, false
+	}
+
+	remaining := time.Until(deadline) - testTimeoutGrace
+	if remaining <= 0 {
+		return 0, false
+	}
+	return remaining, true
+}
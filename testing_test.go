@@ -0,0 +1,195 @@
+package gestalt_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/ovrclk/gestalt"
+	"github.com/ovrclk/gestalt/component"
+	gexec "github.com/ovrclk/gestalt/exec"
+	"github.com/ovrclk/gestalt/vars"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunT(t *testing.T) {
+	trace := make([]string, 0)
+	tracer := func(name string) gestalt.Component {
+		return gestalt.NewComponent(name, func(e gestalt.Evaluator) error {
+			trace = append(trace, e.Path())
+			return nil
+		})
+	}
+
+	generator := exportComponent("a", "foo")
+	check, ran := checkComponent(t, "a", "foo")
+
+	gestalt.RunT(t, component.NewSuite("top").
+		Run(tracer("a")).
+		Run(component.NewGroup("b").
+			Run(component.NewRetry(2, 0).Run(tracer("c"))).
+			Run(generator).
+			WithMeta(vars.NewMeta().Export("a"))).
+		Run(check))
+
+	assert.Equal(t, []string{"/top/a", "/top/b/c"}, trace)
+	assert.True(t, *ran)
+}
+
+// runTCases are run by TestRunT_helper in a child test binary.
+var runTCases = map[string]func() gestalt.Component{
+	"fail": func() gestalt.Component {
+		return component.NewSuite("top").
+			Run(gestalt.NoopComponent("a")).
+			Run(gestalt.NewComponent("b", func(_ gestalt.Evaluator) error {
+				return fmt.Errorf("failed b")
+			}))
+	},
+	"retry": func() gestalt.Component {
+		attempts := 0
+		return component.NewSuite("top").
+			Run(component.NewRetry(3, 0).
+				Run(gestalt.NewComponent("flaky", func(_ gestalt.Evaluator) error {
+					if attempts++; attempts < 2 {
+						return fmt.Errorf("flaky")
+					}
+					return nil
+				})))
+	},
+	"ignore": func() gestalt.Component {
+		return component.NewSuite("top").
+			Run(component.NewIgnore().
+				Run(component.NewGroup("g").
+					Run(gestalt.NewComponent("b", func(_ gestalt.Evaluator) error {
+						return fmt.Errorf("failed b")
+					}))))
+	},
+	"parallel": func() gestalt.Component {
+		return component.NewSuite("top").
+			Run(component.NewParallel("p").
+				Run(gestalt.NoopComponent("a")).
+				Run(gestalt.NewComponent("b", func(_ gestalt.Evaluator) error {
+					return fmt.Errorf("failed b")
+				})))
+	},
+	"background": func() gestalt.Component {
+		return component.NewSuite("top").
+			Run(component.NewGroup("g").
+				Run(component.NewBG().
+					Run(gestalt.NewComponent("server", func(e gestalt.Evaluator) error {
+						<-e.Context().Done()
+						return nil
+					})))).
+			Run(gestalt.NoopComponent("after"))
+	},
+	"interrupt": func() gestalt.Component {
+		pidfile := os.Getenv("GESTALT_RUNT_PIDFILE")
+		return component.NewSuite("top").
+			Run(gestalt.NewComponent("interrupt", func(_ gestalt.Evaluator) error {
+				time.AfterFunc(time.Second/2, func() {
+					syscall.Kill(os.Getpid(), syscall.SIGINT)
+				})
+				return nil
+			})).
+			Run(gexec.SH("server", "echo $$ > "+pidfile+"; exec sleep 30"))
+	},
+	"timeout": func() gestalt.Component {
+		return component.NewSuite("top").
+			Run(gestalt.NewComponent("slow", func(e gestalt.Evaluator) error {
+				select {
+				case <-e.Context().Done():
+					return e.Context().Err()
+				case <-time.After(time.Minute):
+					return nil
+				}
+			}))
+	},
+}
+
+func TestRunT_helper(t *testing.T) {
+	name := os.Getenv("GESTALT_RUNT_CASE")
+	if name == "" {
+		t.SkipNow()
+	}
+	gestalt.RunT(t, runTCases[name]())
+}
+
+func runTCase(t *testing.T, name string, args ...string) (string, bool) {
+	args = append([]string{"-test.run", "TestRunT_helper", "-test.v"}, args...)
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(os.Environ(), "GESTALT_RUNT_CASE="+name)
+	out, err := cmd.CombinedOutput()
+	if _, ok := err.(*exec.ExitError); err != nil && !ok {
+		require.NoError(t, err)
+	}
+	return string(out), err == nil
+}
+
+func TestRunT_outcomes(t *testing.T) {
+	out, passed := runTCase(t, "fail")
+	assert.False(t, passed, out)
+	assert.Contains(t, out, "--- PASS: TestRunT_helper/top/a")
+	assert.Contains(t, out, "--- FAIL: TestRunT_helper/top/b")
+	assert.Contains(t, out, "/top/b: failed b")
+
+	out, passed = runTCase(t, "fail", "-test.run", "TestRunT_helper/top/a")
+	assert.True(t, passed, out)
+	assert.NotContains(t, out, "top/b")
+
+	out, passed = runTCase(t, "retry")
+	assert.True(t, passed, out)
+	assert.NotContains(t, out, "--- FAIL")
+
+	out, passed = runTCase(t, "ignore")
+	assert.True(t, passed, out)
+	assert.NotContains(t, out, "--- FAIL")
+
+	out, passed = runTCase(t, "parallel")
+	assert.False(t, passed, out)
+	assert.Contains(t, out, "--- PASS: TestRunT_helper/top/p/a")
+	assert.Contains(t, out, "--- FAIL: TestRunT_helper/top/p/b")
+	assert.Contains(t, out, "/top/p/b: failed b")
+
+	out, passed = runTCase(t, "parallel", "-test.run", "TestRunT_helper/top/p/a")
+	assert.True(t, passed, out)
+	assert.Contains(t, out, "--- PASS: TestRunT_helper/top/p/a")
+	assert.NotContains(t, out, "top/p/b")
+
+	out, passed = runTCase(t, "background")
+	assert.True(t, passed, out)
+	assert.Contains(t, out, "--- PASS: TestRunT_helper/top/g/server")
+	assert.Contains(t, out, "--- PASS: TestRunT_helper/top/after")
+
+	pidfile, err := ioutil.TempFile("", "gestalt-runt")
+	require.NoError(t, err)
+	pidfile.Close()
+	defer os.Remove(pidfile.Name())
+	os.Setenv("GESTALT_RUNT_PIDFILE", pidfile.Name())
+	defer os.Unsetenv("GESTALT_RUNT_PIDFILE")
+
+	start := time.Now()
+	out, passed = runTCase(t, "interrupt")
+	assert.False(t, passed, out)
+	assert.Contains(t, out, "/top: interrupted by interrupt")
+	assert.True(t, time.Since(start) < time.Second*10)
+
+	buf, err := ioutil.ReadFile(pidfile.Name())
+	require.NoError(t, err)
+	pid, err := strconv.Atoi(strings.TrimSpace(string(buf)))
+	require.NoError(t, err)
+	if !assert.Error(t, syscall.Kill(pid, 0), "command left running") {
+		syscall.Kill(pid, syscall.SIGKILL)
+	}
+
+	out, passed = runTCase(t, "timeout", "-test.timeout", "2s")
+	assert.False(t, passed, out)
+	assert.Contains(t, out, "/top: timed out")
+	assert.NotContains(t, out, "panic: test timed out")
+}
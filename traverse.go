@@ -18,6 +18,15 @@ func TraversePaths(node Component, fn func(string)) {
 	Traverse(node, newSimpleVisitor(fn))
 }
 
+// traverse node with paths continuing from those of path.
+func traversePathsFrom(path *pathVisitor, node Component, fn func(string)) {
+	t := &traverser{
+		path:     path,
+		visitors: []Visitor{path, newSimpleVisitor(fn)},
+	}
+	t.Traverse(node)
+}
+
 func Traverse(node Component, visitors ...Visitor) {
 	newTraverser(visitors...).Traverse(node)
 }
@@ -0,0 +1,91 @@
+package gestalt
+
+import (
+	"fmt"
+	"time"
+)
+
+// Usage describes the resources used by a process run by a component.
+type Usage struct {
+	UserCPU   time.Duration
+	SystemCPU time.Duration
+
+	// maximum resident set size, in bytes.
+	MaxRSS int64
+
+	// signal which terminated the process, if any.
+	Signal string
+}
+
+func (u *Usage) String() string {
+	s := fmt.Sprintf("user %v sys %v maxrss %v", u.UserCPU, u.SystemCPU, fmtBytes(u.MaxRSS))
+	if u.Signal != "" {
+		s += " signal " + u.Signal
+	}
+	return s
+}
+
+// add other to u: CPU times are summed, the larger RSS is kept.
+func (u *Usage) add(other *Usage) {
+	u.UserCPU += other.UserCPU
+	u.SystemCPU += other.SystemCPU
+	if other.MaxRSS > u.MaxRSS {
+		u.MaxRSS = other.MaxRSS
+	}
+	if other.Signal != "" {
+		u.Signal = other.Signal
+	}
+}
+
+// UsageRecorder is implemented by evaluators which account for the
+// resources used by components.
+type UsageRecorder interface {
+	RecordUsage(Usage)
+}
+
+type usageVisitor struct {
+	stack []*Usage
+}
+
+func newUsageVisitor() *usageVisitor {
+	return &usageVisitor{[]*Usage{nil}}
+}
+
+func (h *usageVisitor) Push(_ Traverser, _ Component) {
+	h.stack = append(h.stack, nil)
+}
+
+func (h *usageVisitor) Pop(_ Traverser, _ Component) {
+	if sz := len(h.stack); sz > 1 {
+		h.stack = h.stack[0 : sz-1]
+	}
+}
+
+func (h *usageVisitor) Clone() *usageVisitor {
+	return newUsageVisitor()
+}
+
+func (h *usageVisitor) Current() *Usage {
+	return h.stack[len(h.stack)-1]
+}
+
+func (h *usageVisitor) Add(u Usage) {
+	top := len(h.stack) - 1
+	if h.stack[top] == nil {
+		h.stack[top] = &Usage{}
+	}
+	h.stack[top].add(&u)
+}
+
+func fmtBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%vB", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -3,6 +3,7 @@ package gestalt
 import (
 	"fmt"
 	"io"
+	"os"
 	"time"
 
 	"github.com/fatih/color"
@@ -26,3 +27,19 @@ func fmtDuration(d time.Duration) string {
 
 	return fmt.Sprintf("%d:%d.%.2d", mins, secs, d)
 }
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// open file at path for writing; "-" is stdout.
+func openOutput(path string) (io.WriteCloser, error) {
+	if path == "-" {
+		return nopWriteCloser{os.Stdout}, nil
+	}
+	return os.OpenFile(path, os.O_TRUNC|os.O_WRONLY|os.O_CREATE, 0666)
+}
@@ -0,0 +1,193 @@
+package vars
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// LoadFile reads variables from a YAML, JSON, or dotenv file.  The
+// format is chosen by the file's extension; unknown extensions are
+// read as dotenv.
+func LoadFile(path string) (Vars, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var values map[string]string
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		values, err = parseJSON(buf)
+	case ".yaml", ".yml":
+		values, err = parseYAML(buf)
+	default:
+		values, err = parseDotenv(buf)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", path, err)
+	}
+	return FromMap(values), nil
+}
+
+// FromEnv imports entries of environ (as given by os.Environ()) whose
+// names start with prefix.  Names are converted by removing the prefix,
+// lower-casing, and replacing underscores with dashes:
+// GESTALT_GROUP_NAME becomes group-name.
+func FromEnv(prefix string, environ []string) Vars {
+	v := NewVars()
+	for _, entry := range environ {
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 || !strings.HasPrefix(parts[0], prefix) {
+			continue
+		}
+		key := strings.TrimPrefix(parts[0], prefix)
+		if key == "" {
+			continue
+		}
+		key = strings.Replace(strings.ToLower(key), "_", "-", -1)
+		v.Put(key, parts[1])
+	}
+	return v
+}
+
+type profile struct {
+	Extends []string               `json:"extends" yaml:"extends"`
+	Vars    map[string]interface{} `json:"vars" yaml:"vars"`
+}
+
+// LoadProfile reads the named profile from a YAML or JSON file of
+// profiles:
+//
+//	base:
+//	  vars:
+//	    user-name: u1
+//	staging:
+//	  extends: [base]
+//	  vars:
+//	    group-name: staging
+//
+// Variables of a profile override those of the profiles it extends.
+func LoadProfile(path string, name string) (Vars, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	profiles := make(map[string]profile)
+
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		err = json.Unmarshal(buf, &profiles)
+	} else {
+		err = yaml.Unmarshal(buf, &profiles)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", path, err)
+	}
+
+	v := NewVars()
+	if err := resolveProfile(profiles, name, v, nil); err != nil {
+		return nil, fmt.Errorf("%v: %v", path, err)
+	}
+	return v, nil
+}
+
+func resolveProfile(profiles map[string]profile, name string, v Vars, seen []string) error {
+	for _, prev := range seen {
+		if prev == name {
+			return fmt.Errorf("profile %v: cyclic inheritance (%v)",
+				name, strings.Join(append(seen, name), " -> "))
+		}
+	}
+
+	p, ok := profiles[name]
+	if !ok {
+		return fmt.Errorf("profile %v not found", name)
+	}
+
+	for _, parent := range p.Extends {
+		if err := resolveProfile(profiles, parent, v, append(seen, name)); err != nil {
+			return err
+		}
+	}
+
+	values, err := toStrings(p.Vars)
+	if err != nil {
+		return fmt.Errorf("profile %v: %v", name, err)
+	}
+	v.Merge(FromMap(values))
+
+	return nil
+}
+
+func parseJSON(buf []byte) (map[string]string, error) {
+	raw := make(map[string]interface{})
+	if err := json.Unmarshal(buf, &raw); err != nil {
+		return nil, err
+	}
+	return toStrings(raw)
+}
+
+func parseYAML(buf []byte) (map[string]string, error) {
+	raw := make(map[string]interface{})
+	if err := yaml.Unmarshal(buf, &raw); err != nil {
+		return nil, err
+	}
+	return toStrings(raw)
+}
+
+func parseDotenv(buf []byte) (map[string]string, error) {
+	values := make(map[string]string)
+
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		line = strings.TrimPrefix(line, "export ")
+
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("line %v: expected KEY=VALUE", lineno)
+		}
+
+		key := strings.TrimSpace(parts[0])
+		val := strings.TrimSpace(parts[1])
+
+		if sz := len(val); sz >= 2 && (val[0] == '"' || val[0] == '\'') && val[sz-1] == val[0] {
+			val = val[1 : sz-1]
+		}
+
+		values[key] = val
+	}
+
+	return values, scanner.Err()
+}
+
+func toStrings(raw map[string]interface{}) (map[string]string, error) {
+	values := make(map[string]string)
+	for k, v := range raw {
+		switch v := v.(type) {
+		case nil:
+			values[k] = ""
+		case string:
+			values[k] = v
+		case bool, int, int64, uint64, float64, json.Number:
+			values[k] = fmt.Sprint(v)
+		default:
+			return nil, fmt.Errorf("%v: unsupported value %v", k, v)
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,83 @@
+package vars_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ovrclk/gestalt/vars"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gestalt-vars")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"vars.yaml": "group-name: g1\nuser-count: 3\n",
+		"vars.json": `{"group-name": "g1", "user-count": 3}`,
+		"vars.env":  "# comment\n\nexport group-name=\"g1\"\nuser-count=3\n",
+	}
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+
+		v, err := vars.LoadFile(path)
+		require.NoError(t, err, name)
+		assert.Equal(t, 2, v.Count(), name)
+		assert.Equal(t, "g1", v.Get("group-name"), name)
+		assert.Equal(t, "3", v.Get("user-count"), name)
+	}
+
+	path := filepath.Join(dir, "nested.yaml")
+	require.NoError(t, ioutil.WriteFile(path, []byte("a:\n  b: c\n"), 0644))
+	_, err = vars.LoadFile(path)
+	assert.Error(t, err)
+}
+
+func TestFromEnv(t *testing.T) {
+	v := vars.FromEnv("GESTALT_", []string{
+		"GESTALT_GROUP_NAME=g1",
+		"GESTALT_=ignored",
+		"HOME=/root",
+	})
+	assert.Equal(t, 1, v.Count())
+	assert.Equal(t, "g1", v.Get("group-name"))
+}
+
+func TestLoadProfile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gestalt-vars")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "profiles.yaml")
+	require.NoError(t, ioutil.WriteFile(path, []byte(`
+base:
+  vars:
+    user-name: u1
+    group-name: base
+staging:
+  extends: [base]
+  vars:
+    group-name: staging
+loop-a:
+  extends: [loop-b]
+loop-b:
+  extends: [loop-a]
+`), 0644))
+
+	v, err := vars.LoadProfile(path, "staging")
+	require.NoError(t, err)
+	assert.Equal(t, "u1", v.Get("user-name"))
+	assert.Equal(t, "staging", v.Get("group-name"))
+
+	_, err = vars.LoadProfile(path, "missing")
+	assert.Error(t, err)
+
+	_, err = vars.LoadProfile(path, "loop-a")
+	assert.Error(t, err)
+}
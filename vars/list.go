@@ -0,0 +1,27 @@
+package vars
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// PutList stores values into key as a single (JSON-encoded) value.
+func PutList(v Vars, key string, values []string) {
+	if values == nil {
+		values = []string{}
+	}
+	buf, _ := json.Marshal(values)
+	v.Put(key, string(buf))
+}
+
+// GetList returns the values stored into key with PutList.
+func GetList(v Vars, key string) ([]string, error) {
+	if !v.Has(key) {
+		return nil, fmt.Errorf("list %v not set", key)
+	}
+	var values []string
+	if err := json.Unmarshal([]byte(v.Get(key)), &values); err != nil {
+		return nil, fmt.Errorf("%v is not a list: %v", key, err)
+	}
+	return values, nil
+}
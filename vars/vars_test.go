@@ -89,3 +89,28 @@ func TestExpand(t *testing.T) {
 	}
 
 }
+
+func TestList(t *testing.T) {
+	v := vars.NewVars()
+
+	values := []string{"a", "b c", `"d"`}
+	vars.PutList(v, "list", values)
+
+	if list, err := vars.GetList(v, "list"); err != nil || !reflect.DeepEqual(list, values) {
+		t.Errorf("list not round-tripped: %v %v", list, err)
+	}
+
+	vars.PutList(v, "empty", nil)
+	if list, err := vars.GetList(v, "empty"); err != nil || len(list) != 0 {
+		t.Errorf("empty list not round-tripped: %v %v", list, err)
+	}
+
+	if _, err := vars.GetList(v, "missing"); err == nil {
+		t.Errorf("missing list returned no error")
+	}
+
+	v.Put("scalar", "a")
+	if _, err := vars.GetList(v, "scalar"); err == nil {
+		t.Errorf("scalar returned as a list")
+	}
+}
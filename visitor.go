@@ -13,6 +13,9 @@ import (
 type path struct {
 	base string
 	name string
+
+	// appended to the segments of descendants.
+	suffix string
 }
 
 type pathVisitor struct {
@@ -27,12 +30,16 @@ func (h *pathVisitor) Push(_ Traverser, node Component) {
 	var top path
 
 	base := h.Base()
-	next := base + "/" + node.Name()
+	suffix := h.top().suffix
+	if sc, ok := node.(SuffixComponent); ok && node.IsPassThrough() {
+		suffix += sc.PathSuffix()
+	}
+	next := base + "/" + node.Name() + suffix
 
 	if node.IsPassThrough() {
-		top = path{base, next}
+		top = path{base, next, suffix}
 	} else {
-		top = path{next, next}
+		top = path{next, next, ""}
 	}
 
 	h.stack = append(h.stack, top)
@@ -56,6 +63,20 @@ func (h *pathVisitor) Base() string {
 	return h.top().base
 }
 
+// path of a child of the current component.
+func (h *pathVisitor) Child(name string) string {
+	top := h.top()
+	return top.base + "/" + name + top.suffix
+}
+
+// visitor continuing from the component enclosing the current one.
+func (h *pathVisitor) Parent() *pathVisitor {
+	if sz := len(h.stack); sz > 1 {
+		return &pathVisitor{[]path{h.stack[sz-2]}}
+	}
+	return newPathVisitor()
+}
+
 func (h *pathVisitor) top() path {
 	return h.stack[len(h.stack)-1]
 }
@@ -102,9 +123,20 @@ func newCtxVisitor() *ctxVisitor {
 	return &ctxVisitor{[]*ctxState{top}}
 }
 
-func (h *ctxVisitor) Push(_ Traverser, _ Component) {
-	ctx, cancel := context.WithCancel(h.Current())
-	h.stack = append(h.stack, &ctxState{ctx, cancel})
+func (h *ctxVisitor) Push(_ Traverser, node Component) {
+	var state *ctxState
+	if tc, ok := node.(TimeoutComponent); ok && tc.Timeout() > 0 {
+		state = newTimeoutCtxState(h.Current(), tc.Timeout())
+		state.ctx = context.WithValue(state.ctx, timeoutKey{}, h.Current())
+	} else {
+		ctx, cancel := context.WithCancel(h.Current())
+		state = &ctxState{ctx, cancel}
+	}
+	if ec, ok := node.(EnvComponent); ok && len(ec.Env()) > 0 {
+		env := append(ContextEnv(state.ctx), ec.Env()...)
+		state.ctx = context.WithValue(state.ctx, envKey{}, env)
+	}
+	h.stack = append(h.stack, state)
 }
 
 func (h *ctxVisitor) Pop(_ Traverser, _ Component) {
@@ -127,6 +159,54 @@ func (h *ctxVisitor) Cancel() {
 	h.stack[len(h.stack)-1].cancel()
 }
 
+// cancel function for the context enclosing the current one.
+func (h *ctxVisitor) ParentCancel() context.CancelFunc {
+	if sz := len(h.stack); sz > 1 {
+		return h.stack[sz-2].cancel
+	}
+	return h.stack[0].cancel
+}
+
+// cancel function of the current context.
+func (h *ctxVisitor) CurrentCancel() context.CancelFunc {
+	return h.stack[len(h.stack)-1].cancel
+}
+
+// Derive replaces the current context with one derived from it.
+func (h *ctxVisitor) Derive(fn func(context.Context) context.Context) {
+	top := h.stack[len(h.stack)-1]
+	h.stack[len(h.stack)-1] = &ctxState{fn(top.ctx), top.cancel}
+}
+
+func (h *ctxVisitor) SetTimeout(timeout time.Duration) {
+	h.stack[len(h.stack)-1] = newTimeoutCtxState(h.Current(), timeout)
+}
+
+func newTimeoutCtxState(parent context.Context, timeout time.Duration) *ctxState {
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	return &ctxState{ctx, cancel}
+}
+
+type timeoutKey struct{}
+
+// TimedOut reports whether the deadline of the TimeoutComponent
+// nearest to the component evaluated with ctx has passed, as opposed
+// to one of an enclosing scope.
+func TimedOut(ctx context.Context) bool {
+	parent, ok := ctx.Value(timeoutKey{}).(context.Context)
+	return ok && ctx.Err() == context.DeadlineExceeded &&
+		parent.Err() != context.DeadlineExceeded
+}
+
+type envKey struct{}
+
+// ContextEnv returns the environment defaults provided by the
+// EnvComponents enclosing the component evaluated with ctx.
+func ContextEnv(ctx context.Context) []string {
+	env, _ := ctx.Value(envKey{}).([]string)
+	return append([]string(nil), env...)
+}
+
 type varVisitor struct {
 	stack []vars.Vars
 }
@@ -202,9 +282,35 @@ func (h *errVisitor) Add(err error) {
 	h.stack[len(h.stack)-1] = append(h.stack[len(h.stack)-1], err)
 }
 
+type forkState struct {
+	sync.WaitGroup
+	mtx  sync.Mutex
+	errs []error
+}
+
+func (s *forkState) AddErrors(errs []error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.errs = append(s.errs, errs...)
+}
+
+func (s *forkState) Failed() bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return len(s.errs) > 0
+}
+
+func (s *forkState) drain() []error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	errs := s.errs
+	s.errs = nil
+	return errs
+}
+
 type waitState struct {
-	wg       *sync.WaitGroup
-	children []*sync.WaitGroup
+	forks    *forkState
+	children []*forkState
 }
 
 type waitVisitor struct {
@@ -223,8 +329,8 @@ func (h *waitVisitor) Pop(_ Traverser, _ Component) {
 	top := h.stack[len(h.stack)-1]
 	next := h.stack[len(h.stack)-2]
 
-	if top.wg != nil {
-		next.children = append(next.children, top.wg)
+	if top.forks != nil {
+		next.children = append(next.children, top.forks)
 	}
 	next.children = append(next.children, top.children...)
 
@@ -235,24 +341,72 @@ func (h *waitVisitor) Clone() *waitVisitor {
 	return newWaitVisitor()
 }
 
-func (h *waitVisitor) Current() *sync.WaitGroup {
+func (h *waitVisitor) Current() *forkState {
 	top := h.stack[len(h.stack)-1]
-	if top.wg == nil {
-		top.wg = new(sync.WaitGroup)
+	if top.forks == nil {
+		top.forks = new(forkState)
+	}
+	return top.forks
+}
+
+// Wait for all forked components and return their errors.
+func (h *waitVisitor) Wait() []error {
+	var errs []error
+	for _, forks := range h.all() {
+		forks.Wait()
+		errs = append(errs, forks.drain()...)
+	}
+	return errs
+}
+
+// Failed returns true if any forked component has failed.
+func (h *waitVisitor) Failed() bool {
+	for _, forks := range h.all() {
+		if forks.Failed() {
+			return true
+		}
 	}
-	return top.wg
+	return false
 }
 
-func (h *waitVisitor) Wait() {
+func (h *waitVisitor) all() []*forkState {
 	top := h.stack[len(h.stack)-1]
-	if top.wg != nil {
-		top.wg.Wait()
+	if top.forks == nil {
+		return top.children
 	}
-	for _, child := range top.children {
-		child.Wait()
+	return append([]*forkState{top.forks}, top.children...)
+}
+
+type skipVisitor struct {
+	stack []bool
+}
+
+func newSkipVisitor() *skipVisitor {
+	return &skipVisitor{[]bool{false}}
+}
+
+func (h *skipVisitor) Push(_ Traverser, _ Component) {
+	h.stack = append(h.stack, false)
+}
+
+func (h *skipVisitor) Pop(_ Traverser, _ Component) {
+	if sz := len(h.stack); sz > 1 {
+		h.stack = h.stack[0 : sz-1]
 	}
 }
 
+func (h *skipVisitor) Clone() *skipVisitor {
+	return newSkipVisitor()
+}
+
+func (h *skipVisitor) Current() bool {
+	return h.stack[len(h.stack)-1]
+}
+
+func (h *skipVisitor) Skip() {
+	h.stack[len(h.stack)-1] = true
+}
+
 type nodeVisitor struct {
 	stack []Component
 }
@@ -278,24 +432,36 @@ func (h *nodeVisitor) Root() Component {
 }
 
 type cmpProfile struct {
-	path  string
-	count int
-	total time.Duration
-	avg   time.Duration
+	path   string
+	status Status
+	count  int
+	total  time.Duration
+	avg    time.Duration
+	usage  *Usage
 }
 
 type profileVisitor struct {
+	*profileResults
+	stack []time.Time
+}
+
+// profiles shared by a profileVisitor and its forks.
+type profileResults struct {
+	mtx      sync.Mutex
 	paths    map[string]*cmpProfile
 	profiles []*cmpProfile
-	stack    []time.Time
 }
 
 func newProfileVisitor() *profileVisitor {
 	return &profileVisitor{
-		paths: make(map[string]*cmpProfile),
+		profileResults: &profileResults{paths: make(map[string]*cmpProfile)},
 	}
 }
 
+func (h *profileVisitor) Fork() Visitor {
+	return &profileVisitor{profileResults: h.profileResults}
+}
+
 func (h *profileVisitor) Push(_ Traverser, _ Component) {
 	h.stack = append(h.stack, time.Now())
 }
@@ -308,6 +474,9 @@ func (h *profileVisitor) Pop(t Traverser, _ Component) {
 	}
 	path := t.Path()
 
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
 	profile, ok := h.paths[path]
 	if !ok || profile == nil {
 		profile = &cmpProfile{path: path}
@@ -316,12 +485,21 @@ func (h *profileVisitor) Pop(t Traverser, _ Component) {
 	}
 
 	top := h.stack[topidx]
+	h.stack = h.stack[0:topidx]
 	now := time.Now()
 	delta := now.Sub(top)
 
 	profile.count += 1
 	profile.total += delta
 	profile.avg = profile.total / time.Duration(profile.count)
+	profile.status = statusOf(t)
+
+	if e, ok := t.(*evaluator); ok && e.usage.Current() != nil {
+		if profile.usage == nil {
+			profile.usage = &Usage{}
+		}
+		profile.usage.add(e.usage.Current())
+	}
 }
 
 type traceVisitor struct {
@@ -351,3 +529,7 @@ func (h *traceVisitor) Pop(t Traverser, node Component) {
 func (h *traceVisitor) Clone() *traceVisitor {
 	return &traceVisitor{}
 }
+
+func (h *traceVisitor) Fork() Visitor {
+	return &traceVisitor{h.out}
+}